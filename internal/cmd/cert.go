@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ChrisWiegman/kana-cli/internal/site"
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+	"github.com/logrusorgru/aurora/v4"
+
+	"github.com/spf13/cobra"
+)
+
+var flagCertFormat string
+var flagCertPassword string
+var flagConfirmRegenerate bool
+
+func newCertCommand(kanaSite *site.Site) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:   "cert",
+		Short: "Commands to inspect and manage the certificates Kana uses for https",
+		Args:  cobra.NoArgs,
+	}
+
+	infoCmd := &cobra.Command{
+		Use:   "info",
+		Short: "Show the subject, names, expiry and fingerprint of Kana's certificates",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			err := kanaSite.Settings.PrintCertInfo()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+		},
+		Args: cobra.NoArgs,
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export [file]",
+		Short: "Export the Kana root CA so it can be installed on other devices",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			file := ""
+			if len(args) == 1 {
+				file = args[0]
+			}
+
+			file, err := kanaSite.Settings.ExportRootCert(flagCertFormat, file, flagCertPassword)
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			console.Success(fmt.Sprintf("The Kana root CA has been exported to %s.", file))
+		},
+		Args: cobra.MaximumNArgs(1),
+	}
+
+	regenerateCmd := &cobra.Command{
+		Use:   "regenerate",
+		Short: "Replace the Kana root CA and site certificates with new keys",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			confirmRegenerate := flagConfirmRegenerate
+
+			if !confirmRegenerate {
+				confirmRegenerate = console.PromptConfirm(fmt.Sprintf("Are you sure you want to regenerate Kana's certificates? %s", aurora.Bold(aurora.Yellow("Any device you have installed the current root CA on will need the new one."))), false)
+			}
+
+			if !confirmRegenerate {
+				console.Error(fmt.Errorf("certificate regeneration cancelled. Your certificates have not been changed"), flagVerbose)
+			}
+
+			err := kanaSite.EnsureDocker()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			backupPath, err := kanaSite.RegenerateCerts()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			console.Success(fmt.Sprintf("Your certificates have been regenerated. The old certificates have been moved to %s.", backupPath))
+		},
+		Args: cobra.NoArgs,
+	}
+
+	trustCmd := &cobra.Command{
+		Use:   "trust",
+		Short: "Add the Kana root CA to your system and browser trust stores",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			stores, err := kanaSite.Settings.TrustRootCert()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			console.Success(fmt.Sprintf("The Kana root CA is now trusted by: %s", strings.Join(stores, ", ")))
+		},
+		Args: cobra.NoArgs,
+	}
+
+	untrustCmd := &cobra.Command{
+		Use:   "untrust",
+		Short: "Remove the Kana root CA from your system and browser trust stores",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			stores, err := kanaSite.Settings.UntrustRootCert()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			console.Success(fmt.Sprintf("The Kana root CA has been removed from: %s", strings.Join(stores, ", ")))
+		},
+		Args: cobra.NoArgs,
+	}
+
+	exportCmd.Flags().StringVarP(&flagCertFormat, "format", "f", "pem", "The format to export the certificate in: pem, der or p12")
+	exportCmd.Flags().StringVar(&flagCertPassword, "password", "", "The password to protect a p12 export with")
+	regenerateCmd.Flags().BoolVar(&flagConfirmRegenerate, "confirm-regenerate", false, "Confirm regeneration of your certificates (doesn't require a prompt).")
+
+	cmd.AddCommand(
+		infoCmd,
+		exportCmd,
+		regenerateCmd,
+		trustCmd,
+		untrustCmd,
+	)
+
+	return cmd
+}
@@ -1,12 +1,17 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/ChrisWiegman/kana-cli/internal/site"
 	"github.com/ChrisWiegman/kana-cli/pkg/console"
 
 	"github.com/spf13/cobra"
 )
 
+var flagConfigLocal, flagShowOrigin bool
+
 func newConfigCommand(kanaSite *site.Site) *cobra.Command {
 
 	cmd := &cobra.Command{
@@ -14,20 +19,40 @@ func newConfigCommand(kanaSite *site.Site) *cobra.Command {
 		Short: "View and edit the saved configuration for the app or the local site.",
 		Run: func(cmd *cobra.Command, args []string) {
 
+			// Show where the effective value of every setting, or of the one given, came from
+			if flagShowOrigin {
+
+				if len(args) > 1 {
+					console.Error(fmt.Errorf("--show-origin only accepts the name of a setting"), flagVerbose)
+				}
+
+				key := ""
+				if len(args) == 1 {
+					key = args[0]
+				}
+
+				err := kanaSite.Settings.PrintSettingOrigins(key)
+				if err != nil {
+					console.Error(err, flagVerbose)
+				}
+
+				return
+			}
+
 			// List all content if we don't have args, list the value with 1 arg or set a fresh value with 2 args.
 			// This is similar to how setting git options works
 			switch len(args) {
 			case 0:
 				kanaSite.Settings.ListSettings()
 			case 1:
-				value, err := kanaSite.Settings.GetGlobalSetting(cmd, args)
+				value, err := kanaSite.Settings.GetSetting(args[0], flagConfigLocal)
 				if err != nil {
 					console.Error(err, flagVerbose)
 				}
 
 				console.Println(value)
 			case 2:
-				err := kanaSite.Settings.SetGlobalSetting(cmd, args)
+				err := kanaSite.Settings.SetSetting(args[0], args[1], flagConfigLocal)
 				if err != nil {
 					console.Error(err, flagVerbose)
 				}
@@ -36,5 +61,80 @@ func newConfigCommand(kanaSite *site.Site) *cobra.Command {
 		Args: cobra.RangeArgs(0, 2),
 	}
 
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check the global config and the current site's config file for unknown keys and invalid values.",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			configFiles, err := kanaSite.Settings.ValidateConfig()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			console.Success(fmt.Sprintf("Your config is valid: %s", strings.Join(configFiles, ", ")))
+		},
+		Args: cobra.NoArgs,
+	}
+
+	unsetCmd := &cobra.Command{
+		Use:   "unset <setting>",
+		Short: "Remove a setting from the config file so that its default applies again.",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			err := kanaSite.Settings.UnsetSetting(args[0], flagConfigLocal)
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			console.Success(fmt.Sprintf("%s has been reset to its default.", args[0]))
+		},
+		Args: cobra.ExactArgs(1),
+	}
+
+	editCmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Open the config file in your editor ($VISUAL or $EDITOR) and validate it when you're done.",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			configFile, err := kanaSite.Settings.EditConfig(flagConfigLocal)
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			console.Success(fmt.Sprintf("Your changes to %s have been saved.", configFile))
+		},
+		Args: cobra.NoArgs,
+	}
+
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade the global config and the current site's config file if they were written by an older version of Kana.",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			configFiles, err := kanaSite.Settings.MigrateConfig()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			if len(configFiles) == 0 {
+				console.Success("Your config files are already up to date.")
+				return
+			}
+
+			console.Success(fmt.Sprintf("Your config files have been migrated: %s", strings.Join(configFiles, ", ")))
+		},
+		Args: cobra.NoArgs,
+	}
+
+	cmd.AddCommand(
+		validateCmd,
+		unsetCmd,
+		editCmd,
+		migrateCmd,
+	)
+
+	cmd.PersistentFlags().BoolVarP(&flagConfigLocal, "local", "l", false, "Use the current site's config file instead of the global config.")
+	cmd.Flags().BoolVar(&flagShowOrigin, "show-origin", false, "Show the effective value of each setting, where it came from and the values it overrode.")
+
 	return cmd
 }
@@ -47,6 +47,12 @@ func newDestroyCommand(kanaSite *site.Site) *cobra.Command {
 					console.Error(err, flagVerbose)
 				}
 
+				// Remove the site's certificate so Traefik stops serving it.
+				err = kanaSite.Settings.RemoveSiteCerts()
+				if err != nil {
+					console.Error(err, flagVerbose)
+				}
+
 				console.Success(fmt.Sprintf("Your site, %s, has been completely destroyed.", aurora.Bold(aurora.Blue(kanaSite.Settings.Name))))
 				return
 			}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"path"
 
 	"github.com/ChrisWiegman/kana-cli/internal/site"
 	"github.com/ChrisWiegman/kana-cli/pkg/console"
@@ -31,7 +30,7 @@ func newExportCommand(kanaSite *site.Site) *cobra.Command {
 				console.Error(err, flagVerbose)
 			}
 
-			console.Success(fmt.Sprintf("Your config has been exported to %s", path.Join(kanaSite.Settings.WorkingDirectory, ".kana.json")))
+			console.Success(fmt.Sprintf("Your config has been exported to %s", kanaSite.Settings.GetConfigFile(true)))
 		},
 		Args: cobra.ArbitraryArgs,
 	}
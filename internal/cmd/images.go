@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"github.com/ChrisWiegman/kana-cli/internal/site"
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+
+	"github.com/spf13/cobra"
+)
+
+func newImagesCommand(kanaSite *site.Site) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:   "images",
+		Short: "Commands to list, pull and update the container images used by Kana",
+		Args:  cobra.NoArgs,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the images used for the current site and whether they have been pulled",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			err := kanaSite.EnsureDocker()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			err = kanaSite.ListImages()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+		},
+		Args: cobra.NoArgs,
+	}
+
+	pullCmd := &cobra.Command{
+		Use:   "pull",
+		Short: "Pull any images the current site needs that aren't available yet",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			err := kanaSite.EnsureDocker()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			err = kanaSite.PullImages()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			console.Success("All images have been pulled.")
+		},
+		Args: cobra.NoArgs,
+	}
+
+	updateCmd := &cobra.Command{
+		Use:   "update",
+		Short: "Remove and pull the images the current site uses again to pick up changes to their tags",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			err := kanaSite.EnsureDocker()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			err = kanaSite.UpdateImages()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			console.Success("Images have been updated.")
+		},
+		Args: cobra.NoArgs,
+	}
+
+	cmd.AddCommand(
+		listCmd,
+		pullCmd,
+		updateCmd,
+	)
+
+	return cmd
+}
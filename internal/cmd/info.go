@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/ChrisWiegman/kana-cli/internal/site"
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+
+	"github.com/spf13/cobra"
+)
+
+func newInfoCommand(kanaSite *site.Site) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:   "info",
+		Short: "Show details of the current site including the version of WordPress it is running.",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			err := kanaSite.EnsureDocker()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			err = kanaSite.PrintSiteInfo()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+		},
+		Args: cobra.NoArgs,
+	}
+
+	commandsRequiringSite = append(commandsRequiringSite, cmd.Use)
+
+	return cmd
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ChrisWiegman/kana-cli/internal/site"
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+
+	"github.com/spf13/cobra"
+)
+
+var flagInitYes bool
+
+func newInitCommand(kanaSite *site.Site) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create a .kana.json file for the current folder by answering a few questions.",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			configFile, err := kanaSite.Settings.InitConfig(flagInitYes)
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			console.Success(fmt.Sprintf("Your config has been saved to %s. Run `kana start` to start your site.", configFile))
+		},
+		Args: cobra.NoArgs,
+	}
+
+	cmd.Flags().BoolVarP(&flagInitYes, "yes", "y", false, "Accept the detected defaults without asking any questions.")
+
+	return cmd
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ChrisWiegman/kana-cli/internal/site"
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+	"github.com/logrusorgru/aurora/v4"
+
+	"github.com/spf13/cobra"
+)
+
+var flagForcePreset bool
+
+func newPresetCommand(kanaSite *site.Site) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:   "preset",
+		Short: "Commands to list, show and save presets for starting new sites with `kana start --preset`",
+		Args:  cobra.NoArgs,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the presets in the global config and the presets folder",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			err := kanaSite.Settings.ListPresets()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+		},
+		Args: cobra.NoArgs,
+	}
+
+	showCmd := &cobra.Command{
+		Use:   "show <preset>",
+		Short: "Show the settings in a preset",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			err := kanaSite.Settings.PrintPreset(args[0])
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+		},
+		Args: cobra.ExactArgs(1),
+	}
+
+	saveCmd := &cobra.Command{
+		Use:   "save <preset>",
+		Short: "Save the current site's running config as a preset",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			err := kanaSite.EnsureDocker()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			if !kanaSite.IsSiteRunning() {
+				console.Error(fmt.Errorf("the preset save command only works on a running site.  Please run 'kana start' to start the site"), flagVerbose)
+			}
+
+			file, err := kanaSite.SavePreset(args[0], flagForcePreset)
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			console.Success(fmt.Sprintf("The %s preset has been saved to %s. Use `kana start --preset %s` to start a site with it.", aurora.Bold(aurora.Blue(args[0])), file, args[0]))
+		},
+		Args: cobra.ExactArgs(1),
+	}
+
+	saveCmd.Flags().BoolVarP(&flagForcePreset, "force", "f", false, "Replace the preset if it already exists.")
+
+	commandsRequiringSite = append(commandsRequiringSite, saveCmd.Use)
+
+	cmd.AddCommand(
+		listCmd,
+		showCmd,
+		saveCmd,
+	)
+
+	return cmd
+}
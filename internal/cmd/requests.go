@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/ChrisWiegman/kana-cli/internal/site"
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+
+	"github.com/spf13/cobra"
+)
+
+var flagFollow bool
+var flagStatus string
+
+func newRequestsCommand(kanaSite *site.Site) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:   "requests",
+		Short: "Show the requests made to the current site including their status and duration.",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			err := kanaSite.ShowRequests(flagFollow, flagStatus)
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+		},
+		Args: cobra.NoArgs,
+	}
+
+	commandsRequiringSite = append(commandsRequiringSite, cmd.Use)
+
+	cmd.Flags().BoolVarP(&flagFollow, "follow", "f", false, "Keep watching for new requests until interrupted.")
+	cmd.Flags().StringVarP(&flagStatus, "status", "s", "", "Only show requests with the given status code (such as 404) or class (such as 5xx).")
+
+	return cmd
+}
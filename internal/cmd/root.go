@@ -9,6 +9,7 @@ import (
 
 var flagName string
 var flagVerbose bool
+var flagShowSecrets bool
 var commandsRequiringSite []string
 
 func Execute() {
@@ -31,18 +32,26 @@ func Execute() {
 	// Add the "name" flag to allow for sites not connected to the local directory
 	cmd.PersistentFlags().StringVarP(&flagName, "name", "n", "", "Specify a name for the site, used to override using the current folder.")
 	cmd.PersistentFlags().BoolVarP(&flagVerbose, "verbose", "v", false, "Display debugging information along with detailed command output")
+	cmd.PersistentFlags().BoolVar(&flagShowSecrets, "show-secrets", false, "Show passwords and other secrets in output instead of hiding them")
 
 	// Register the subcommands
 	cmd.AddCommand(
+		newInitCommand(site),
 		newStartCommand(site),
 		newStopCommand(site),
 		newOpenCommand(site),
+		newInfoCommand(site),
 		newWPCommand(site),
 		newDestroyCommand(site),
 		newConfigCommand(site),
 		newExportCommand(site),
 		newVersionCommand(),
 		newDbCommand(site),
+		newShareCommand(site),
+		newImagesCommand(site),
+		newRequestsCommand(site),
+		newCertCommand(site),
+		newPresetCommand(site),
 	)
 
 	// Execute anything we need to
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ChrisWiegman/kana-cli/internal/site"
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+
+	"github.com/spf13/cobra"
+)
+
+var flagSharePort int
+
+func newShareCommand(kanaSite *site.Site) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:   "share",
+		Short: "Share the current site with phones and tablets on your local network.",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			err := kanaSite.EnsureDocker()
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+
+			if !kanaSite.IsSiteRunning() {
+				console.Error(fmt.Errorf("the share command only works on a running site. Please run 'kana start --lan' to start the site"), flagVerbose)
+			}
+
+			err = kanaSite.ShareSite(flagSharePort)
+			if err != nil {
+				console.Error(err, flagVerbose)
+			}
+		},
+		Args: cobra.NoArgs,
+	}
+
+	commandsRequiringSite = append(commandsRequiringSite, cmd.Use)
+
+	cmd.Flags().IntVar(&flagSharePort, "port", 8090, "The port used to serve the Kana root CA to devices on your network.")
+
+	return cmd
+}
@@ -46,6 +46,8 @@ func newStartCommand(kanaSite *site.Site) *cobra.Command {
 	cmd.Flags().BoolVarP(&startFlags.IsPlugin, "plugin", "p", false, "Run the site as a plugin using the current folder as the plugin source.")
 	cmd.Flags().BoolVarP(&startFlags.IsTheme, "theme", "t", false, "Run the site as a theme using the current folder as the theme source.")
 	cmd.Flags().BoolVarP(&startFlags.Local, "local", "l", false, "Installs the WordPress files in your current path at ./wordpress instead of the global app path.")
+	cmd.Flags().BoolVar(&startFlags.Lan, "lan", false, "Make the site reachable from other devices on your local network (see `kana share`).")
+	cmd.Flags().StringVar(&startFlags.Preset, "preset", "", "Start the site with the settings from a preset (see `kana preset list`).")
 
 	return cmd
 }
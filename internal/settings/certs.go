@@ -0,0 +1,481 @@
+package settings
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+	"github.com/ChrisWiegman/kana-cli/pkg/minica"
+	"github.com/ChrisWiegman/kana-cli/pkg/truststore"
+
+	"github.com/aquasecurity/table"
+	"github.com/mitchellh/go-homedir"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// External CAs, such as mkcert's or a corporate CA, are trusted and untrusted by the tools that manage them
+var errExternalCA = fmt.Errorf("your sites are signed by an external CA (ca.cert, ca.key or ca.mkcert), which is managed outside Kana. Use the tool that manages it to change where it is trusted")
+
+type certDescription struct {
+	name, file string
+}
+
+// newTrustStore Returns the trust store for the current platform. Tests replace it with a fake store.
+var newTrustStore = truststore.New
+
+var validCertFormats = []string{
+	"pem",
+	"der",
+	"p12",
+}
+
+// ExportRootCert Writes the root CA to a file in the given format so it can be installed on other devices
+func (s *Settings) ExportRootCert(format, file, password string) (string, error) {
+
+	if !isValidString(format, validCertFormats) {
+		return "", fmt.Errorf("please choose a valid format: %s", strings.Join(validCertFormats, ", "))
+	}
+
+	if file == "" {
+		file = fmt.Sprintf("%s.%s", rootCertFileName, format)
+	}
+
+	if !path.IsAbs(file) {
+		file = path.Join(s.WorkingDirectory, file)
+	}
+
+	rootCert, err := s.GetRootCertPath()
+	if err != nil {
+		return "", err
+	}
+
+	cert, err := readCertFile(rootCert)
+	if err != nil {
+		return "", err
+	}
+
+	var contents []byte
+
+	switch format {
+	case "pem":
+		contents = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	case "der":
+		contents = cert.Raw
+	case "p12":
+		contents, err = pkcs12.EncodeTrustStore(rand.Reader, []*x509.Certificate{cert}, password)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return file, os.WriteFile(file, contents, 0644)
+}
+
+// PrintCertInfo Prints the details of the root CA and every certificate it has issued
+func (s *Settings) PrintCertInfo() error {
+
+	certPath := path.Join(s.AppDirectory, "certs")
+
+	rootCert, err := s.GetRootCertPath()
+	if err != nil {
+		return err
+	}
+
+	certs := []certDescription{
+		{"Root CA", rootCert},
+		{"Site", path.Join(certPath, s.SiteCert)},
+		{"LAN", path.Join(certPath, s.LanCert)},
+	}
+
+	siteCerts, err := filepath.Glob(path.Join(certPath, siteCertFolder, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	for _, siteCert := range siteCerts {
+		certs = append(certs, certDescription{
+			fmt.Sprintf("Site (%s)", strings.TrimSuffix(filepath.Base(siteCert), ".pem")),
+			siteCert,
+		})
+	}
+
+	t := table.New(os.Stdout)
+
+	t.SetHeaders("Certificate", "Subject", "Names", "Expires", "SHA-256 Fingerprint")
+
+	legacyRoot := false
+
+	for _, certFile := range certs {
+
+		cert, err := readCertFile(certFile.file)
+		if err != nil {
+			// Certificates such as the LAN certificate are only created when needed
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return err
+		}
+
+		expires := cert.NotAfter.Local().Format("2006-01-02")
+		if time.Now().After(cert.NotAfter) {
+			expires = fmt.Sprintf("%s (expired)", expires)
+		}
+
+		// The root doesn't have names of its own so list the domains it is limited to instead
+		names := cert.DNSNames
+		if cert.IsCA {
+			names = cert.PermittedDNSDomains
+			legacyRoot = s.isLegacyRoot(cert)
+		}
+
+		t.AddRow(
+			certFile.name,
+			console.Bold(cert.Subject.CommonName),
+			strings.Join(names, "\n"),
+			expires,
+			formatFingerprint(sha256.Sum256(cert.Raw)))
+	}
+
+	t.Render()
+
+	if legacyRoot {
+		console.Warn("Your Kana root CA doesn't use the configured ca.key_type or isn't limited to Kana's domains. Run `kana cert regenerate` to replace it.")
+	}
+
+	return nil
+}
+
+// RegenerateCerts Replaces the root CA and site certificate with new keys, keeping a backup of the old ones. Only the site certificate is replaced when signing with an external CA.
+func (s *Settings) RegenerateCerts() (string, error) {
+
+	certPath := path.Join(s.AppDirectory, "certs")
+	backupPath := path.Join(certPath, fmt.Sprintf("backup-%s", time.Now().Format("20060102-150405")))
+
+	if s.isExternalCA() {
+
+		err := os.MkdirAll(backupPath, 0750)
+		if err != nil {
+			return "", err
+		}
+
+		err = moveCertFiles(certPath, backupPath, []string{s.SiteCert, s.SiteKey, s.LanCert, s.LanKey, siteCertFolder}, true)
+		if err != nil {
+			return backupPath, err
+		}
+
+		_, err = s.EnsureSSLCerts()
+		if err != nil {
+			return backupPath, err
+		}
+
+		return backupPath, s.EnsureStaticConfigFiles()
+	}
+
+	err := os.MkdirAll(certPath, 0750)
+	if err != nil {
+		return "", err
+	}
+
+	// Generate everything in a temporary directory first so a failure never leaves us without working certificates
+	newCertPath, err := os.MkdirTemp(certPath, "regenerate-")
+	if err != nil {
+		return "", err
+	}
+
+	defer os.RemoveAll(newCertPath)
+
+	certInfo, err := s.getCertInfo(newCertPath, getWildcardDomains(s.AppDomain), s.SiteCert, s.SiteKey)
+	if err != nil {
+		return "", err
+	}
+
+	err = minica.GenCerts(certInfo)
+	if err != nil {
+		return "", err
+	}
+
+	// Stop trusting the old root before it is replaced
+	if _, err = os.Stat(path.Join(certPath, s.RootCert)); err == nil {
+		_, err = s.UntrustRootCert()
+		if err != nil {
+			console.Warn(fmt.Sprintf("Unable to remove the old Kana root CA from your trust stores: %s", err))
+		}
+	}
+
+	err = os.MkdirAll(backupPath, 0750)
+	if err != nil {
+		return "", err
+	}
+
+	err = moveCertFiles(certPath, backupPath, []string{s.RootKey, s.RootCert, s.SiteCert, s.SiteKey, s.LanCert, s.LanKey, siteCertFolder}, true)
+	if err != nil {
+		return backupPath, err
+	}
+
+	err = moveCertFiles(newCertPath, certPath, []string{s.RootKey, s.RootCert, s.SiteCert, s.SiteKey}, false)
+	if err != nil {
+		return backupPath, err
+	}
+
+	err = s.installRootCert()
+	if err != nil {
+		return backupPath, err
+	}
+
+	// The LAN and per-site certificates were signed by the old root and will be reissued the next time they are needed
+	return backupPath, s.EnsureStaticConfigFiles()
+}
+
+// GetRootCertPath Returns the path of the CA certificate used to sign site certificates
+func (s *Settings) GetRootCertPath() (string, error) {
+
+	caCert, _, err := s.getCAFiles()
+
+	return caCert, err
+}
+
+// TrustRootCert Adds the root CA to the trust stores for the current platform, returning the stores it was added to
+func (s *Settings) TrustRootCert() ([]string, error) {
+
+	if s.isExternalCA() {
+		return []string{}, errExternalCA
+	}
+
+	store, err := s.getTrustStore()
+	if err != nil {
+		return []string{}, err
+	}
+
+	rootCert, err := s.GetRootCertPath()
+	if err != nil {
+		return []string{}, err
+	}
+
+	return store.Install(rootCert)
+}
+
+// UntrustRootCert Removes the root CA from the trust stores for the current platform, returning the stores it was removed from
+func (s *Settings) UntrustRootCert() ([]string, error) {
+
+	if s.isExternalCA() {
+		return []string{}, errExternalCA
+	}
+
+	store, err := s.getTrustStore()
+	if err != nil {
+		return []string{}, err
+	}
+
+	rootCert, err := s.GetRootCertPath()
+	if err != nil {
+		return []string{}, err
+	}
+
+	return store.Uninstall(rootCert)
+}
+
+// formatFingerprint Formats a fingerprint as colon separated hex bytes
+func formatFingerprint(fingerprint [sha256.Size]byte) string {
+
+	parts := make([]string, len(fingerprint))
+
+	for i, b := range fingerprint {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+
+	return strings.Join(parts, ":")
+}
+
+// getTrustStore Returns the trust store for the current platform
+func (s *Settings) getTrustStore() (truststore.Store, error) {
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	return newTrustStore(home, rootCertName, rootCertFileName)
+}
+
+// getCAFiles Returns the paths of the CA certificate and key, which are Kana's own unless an external CA has been configured
+func (s *Settings) getCAFiles() (string, string, error) {
+
+	if s.CACert != "" || s.CAKey != "" {
+
+		if s.CACert == "" || s.CAKey == "" {
+			return "", "", fmt.Errorf("both ca.cert and ca.key need to be set to use your own CA")
+		}
+
+		return s.CACert, s.CAKey, nil
+	}
+
+	if s.CAMkcert {
+
+		output, err := exec.Command("mkcert", "-CAROOT").Output()
+		if err != nil {
+			return "", "", fmt.Errorf("unable to find the mkcert CA. Please make sure mkcert is installed: %s", err)
+		}
+
+		// Save the result so we only need to ask mkcert once
+		caRoot := strings.TrimSpace(string(output))
+		s.CACert = path.Join(caRoot, "rootCA.pem")
+		s.CAKey = path.Join(caRoot, "rootCA-key.pem")
+
+		return s.CACert, s.CAKey, nil
+	}
+
+	certPath := path.Join(s.AppDirectory, "certs")
+
+	return path.Join(certPath, s.RootCert), path.Join(certPath, s.RootKey), nil
+}
+
+// getCertInfo Returns the details minica needs to sign a certificate for the domains into certDir
+func (s *Settings) getCertInfo(certDir string, domains []string, certFile, keyFile string) (minica.CertInfo, error) {
+
+	certInfo := minica.CertInfo{
+		CertDir:          certDir,
+		RootKey:          s.RootKey,
+		RootCert:         s.RootCert,
+		SiteCert:         certFile,
+		SiteKey:          keyFile,
+		Domains:          domains,
+		KeyType:          s.CAKeyType,
+		PermittedDomains: s.getPermittedDomains(),
+	}
+
+	if s.isExternalCA() {
+
+		caCert, caKey, err := s.getCAFiles()
+		if err != nil {
+			return certInfo, err
+		}
+
+		certInfo.RootCert = caCert
+		certInfo.RootKey = caKey
+		certInfo.ExternalCA = true
+	}
+
+	return certInfo, nil
+}
+
+// getPermittedDomains Returns the domains a new Kana root CA is allowed to sign certificates for: the app domain, any domains configured in ca.domains and,
+// while LAN sharing is enabled, the LAN domain, which is limited to this machine's own nip.io name rather than all of nip.io.
+func (s *Settings) getPermittedDomains() []string {
+
+	domains := append([]string{s.AppDomain}, s.CADomains...)
+
+	if !s.Lan {
+		return domains
+	}
+
+	// Without a LAN address there is nothing to share on so EnsureLANCerts will report the problem
+	lanDomain, err := s.GetLANDomain()
+	if err == nil {
+		domains = append(domains, lanDomain)
+	}
+
+	return domains
+}
+
+// validateDomains Checks that every domain the root CA should be allowed to sign certificates for is a valid domain name
+func validateDomains(value interface{}) error {
+
+	for _, domain := range value.([]interface{}) {
+
+		err := validateTag("fqdn", "a valid domain")(domain)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkNameConstraints Makes sure the CA is allowed to sign certificates for all of the domains
+func (s *Settings) checkNameConstraints(domains []string) error {
+
+	rootCert, err := s.GetRootCertPath()
+	if err != nil {
+		return err
+	}
+
+	root, err := readCertFile(rootCert)
+	if err != nil {
+		// Missing or unreadable roots are reported when we try to sign with them
+		return nil
+	}
+
+	for _, domain := range domains {
+		if !isPermittedDomain(root, domain) {
+			return fmt.Errorf("the root CA isn't allowed to sign certificates for %s. Run `kana cert regenerate` to create a new root CA", domain)
+		}
+	}
+
+	return nil
+}
+
+// isLegacyRoot Checks if the Kana root CA predates name constraints or uses a different key type than configured
+func (s *Settings) isLegacyRoot(root *x509.Certificate) bool {
+
+	if s.isExternalCA() {
+		return false
+	}
+
+	keyAlgorithm := x509.ECDSA
+	if s.CAKeyType == minica.KeyTypeRSA {
+		keyAlgorithm = x509.RSA
+	}
+
+	return len(root.PermittedDNSDomains) == 0 || root.PublicKeyAlgorithm != keyAlgorithm
+}
+
+// isPermittedDomain Checks if a domain, which may be a wildcard, is within the root's permitted DNS domains
+func isPermittedDomain(root *x509.Certificate, domain string) bool {
+
+	if len(root.PermittedDNSDomains) == 0 {
+		return true
+	}
+
+	domain = strings.TrimPrefix(strings.ToLower(domain), "*.")
+
+	for _, permitted := range root.PermittedDNSDomains {
+
+		permitted = strings.TrimPrefix(strings.ToLower(permitted), ".")
+
+		if domain == permitted || strings.HasSuffix(domain, fmt.Sprintf(".%s", permitted)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isExternalCA Checks if site certificates are signed by a CA Kana doesn't manage
+func (s *Settings) isExternalCA() bool {
+
+	return s.CACert != "" || s.CAKey != "" || s.CAMkcert
+}
+
+// moveCertFiles Moves certificate files from one directory to another, optionally skipping any that don't exist
+func moveCertFiles(source, destination string, files []string, skipMissing bool) error {
+
+	for _, file := range files {
+
+		err := os.Rename(path.Join(source, file), path.Join(destination, file))
+		if err != nil && !(skipMissing && os.IsNotExist(err)) {
+			return err
+		}
+	}
+
+	return nil
+}
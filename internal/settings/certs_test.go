@@ -0,0 +1,218 @@
+package settings
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/ChrisWiegman/kana-cli/pkg/minica"
+	"github.com/ChrisWiegman/kana-cli/pkg/truststore"
+)
+
+// fakeTrustStore Records the certificates Kana tries to install into or remove from the system trust stores
+type fakeTrustStore struct {
+	installed, uninstalled []string
+}
+
+func (f *fakeTrustStore) Install(certFile string) ([]string, error) {
+
+	f.installed = append(f.installed, certFile)
+
+	return []string{"fake"}, nil
+}
+
+func (f *fakeTrustStore) Uninstall(certFile string) ([]string, error) {
+
+	f.uninstalled = append(f.uninstalled, certFile)
+
+	return []string{"fake"}, nil
+}
+
+// useFakeTrustStore Replaces the platform trust store with a fake one for the rest of the test
+func useFakeTrustStore(t *testing.T) *fakeTrustStore {
+
+	store := &fakeTrustStore{}
+	original := newTrustStore
+
+	newTrustStore = func(home, name, fileName string) (truststore.Store, error) {
+		return store, nil
+	}
+
+	t.Cleanup(func() {
+		newTrustStore = original
+	})
+
+	return store
+}
+
+func TestTrustExternalCA(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		settings Settings
+	}{
+		{"ca.cert and ca.key", Settings{CACert: "/etc/ssl/corporate.pem", CAKey: "/etc/ssl/corporate-key.pem"}},
+		{"ca.mkcert", Settings{CAMkcert: true}},
+	}
+
+	for _, test := range tests {
+
+		store := useFakeTrustStore(t)
+
+		_, err := test.settings.TrustRootCert()
+		if err == nil {
+			t.Errorf("%s: expected trusting an external CA to fail", test.name)
+		}
+
+		_, err = test.settings.UntrustRootCert()
+		if err == nil {
+			t.Errorf("%s: expected untrusting an external CA to fail", test.name)
+		}
+
+		if len(store.installed) != 0 || len(store.uninstalled) != 0 {
+			t.Errorf("%s: expected the trust store to be left alone; installed %v, uninstalled %v", test.name, store.installed, store.uninstalled)
+		}
+	}
+}
+
+func TestTrustKanaCA(t *testing.T) {
+
+	store := useFakeTrustStore(t)
+	settings := Settings{AppDirectory: t.TempDir(), RootCert: "kana.root.pem"}
+
+	_, err := settings.TrustRootCert()
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = settings.UntrustRootCert()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(store.installed) != 1 || len(store.uninstalled) != 1 {
+		t.Errorf("Expected the Kana root CA to be installed and removed once; installed %v, uninstalled %v", store.installed, store.uninstalled)
+	}
+}
+
+// newTestRoot Creates a Kana root CA and site certificate in a temporary app directory
+func newTestRoot(t *testing.T, settings *Settings) {
+
+	settings.AppDirectory = t.TempDir()
+	settings.RootCert = rootCert
+	settings.RootKey = rootKey
+
+	certPath := path.Join(settings.AppDirectory, "certs")
+
+	err := os.MkdirAll(certPath, 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certInfo, err := settings.getCertInfo(certPath, getWildcardDomains(settings.AppDomain), siteCert, siteKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = minica.GenCerts(certInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPermittedDomains(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		settings Settings
+		expected []string
+	}{
+		{"LAN disabled", Settings{AppDomain: "sites.kana.li"}, []string{"sites.kana.li"}},
+		{"LAN disabled with a hostname", Settings{AppDomain: "sites.kana.li", LanHostname: "kana.example.com"}, []string{"sites.kana.li"}},
+		{"LAN enabled with a hostname", Settings{AppDomain: "sites.kana.li", Lan: true, LanHostname: "kana.example.com"}, []string{"sites.kana.li", "kana.example.com"}},
+		{"custom domains", Settings{AppDomain: "sites.kana.li", CADomains: []string{"home.lan"}}, []string{"sites.kana.li", "home.lan"}},
+		{"custom domains and LAN", Settings{AppDomain: "sites.kana.li", CADomains: []string{"home.lan"}, Lan: true, LanHostname: "kana.home.lan"}, []string{"sites.kana.li", "home.lan", "kana.home.lan"}},
+	}
+
+	for _, test := range tests {
+
+		permitted := test.settings.getPermittedDomains()
+		if !reflect.DeepEqual(permitted, test.expected) {
+			t.Errorf("%s: expected the root to permit %v; got %v", test.name, test.expected, permitted)
+		}
+	}
+}
+
+func TestRootRejectsOtherNipIODomains(t *testing.T) {
+
+	settings := Settings{AppDomain: "sites.kana.li"}
+	newTestRoot(t, &settings)
+
+	err := settings.checkNameConstraints(getWildcardDomains("1.2.3.4.nip.io"))
+	if err == nil {
+		t.Error("Expected a root created without LAN sharing to reject 1.2.3.4.nip.io")
+	}
+
+	settings = Settings{AppDomain: "sites.kana.li", Lan: true, LanHostname: "192.168.1.20.nip.io"}
+	newTestRoot(t, &settings)
+
+	err = settings.checkNameConstraints(getWildcardDomains("192.168.1.20.nip.io"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = settings.checkNameConstraints(getWildcardDomains("1.2.3.4.nip.io"))
+	if err == nil {
+		t.Error("Expected a root created for 192.168.1.20.nip.io to reject 1.2.3.4.nip.io")
+	}
+}
+
+func TestLANCertsRequireRegeneratingTheRoot(t *testing.T) {
+
+	settings := Settings{AppDomain: "sites.kana.li", LanCert: "kana.lan.pem", LanKey: "kana.lan.key"}
+	newTestRoot(t, &settings)
+
+	rootFile := path.Join(settings.AppDirectory, "certs", rootCert)
+	contents := readTestFile(t, rootFile)
+
+	_, err := settings.EnsureLANCerts("192.168.1.20.nip.io")
+	if err == nil {
+		t.Error("Expected signing a LAN domain the root doesn't permit to fail")
+	}
+
+	if readTestFile(t, rootFile) != contents {
+		t.Error("Expected the root CA to be left alone")
+	}
+}
+
+func TestRootPermitsCustomDomains(t *testing.T) {
+
+	settings := Settings{AppDomain: "sites.kana.li", CADomains: []string{"home.lan"}}
+	newTestRoot(t, &settings)
+
+	err := settings.checkNameConstraints(getWildcardDomains("kana.home.lan"))
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateDomains(t *testing.T) {
+
+	tests := []struct {
+		domains []interface{}
+		valid   bool
+	}{
+		{[]interface{}{}, true},
+		{[]interface{}{"home.lan", "dev.example.com"}, true},
+		{[]interface{}{"not a domain"}, false},
+	}
+
+	for _, test := range tests {
+
+		err := validateDomains(test.domains)
+		if (err == nil) != test.valid {
+			t.Errorf("Expected %v to be valid: %t; got %v", test.domains, test.valid, err)
+		}
+	}
+}
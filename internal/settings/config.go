@@ -1,102 +1,316 @@
 package settings
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
-	"strconv"
+	"os/exec"
 	"strings"
 
 	"github.com/ChrisWiegman/kana-cli/pkg/console"
 
 	"github.com/aquasecurity/table"
-	"github.com/go-playground/validator/v10"
-	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
-// GetGlobalSetting Retrieves a global setting for the "config" command
-func (s *Settings) GetGlobalSetting(md *cobra.Command, args []string) (string, error) {
+// GetSetting Retrieves a global setting, or a setting for the current site when local is true, for the "config" command
+func (s *Settings) GetSetting(key string, local bool) (string, error) {
 
-	if !s.global.IsSet(args[0]) {
-		return "", fmt.Errorf("invalid setting. Please enter a valid key to get")
+	schema, configViper, err := s.getConfigSetting(key, local)
+	if err != nil {
+		return "", err
+	}
+
+	switch schema.valueType {
+	case listSetting:
+		return strings.Join(configViper.GetStringSlice(key), ","), nil
+	case packageSetting:
+		packages := getPackagesConfig(getPackages(configViper.Get(key), isActiveByDefault(key)), isActiveByDefault(key))
+
+		// Packages that are all plain sources are shown the way they are entered, otherwise as JSON so the objects can be read back
+		sources := []string{}
+
+		for _, configPackage := range packages {
+			if source, ok := configPackage.(string); ok {
+				sources = append(sources, source)
+			}
+		}
+
+		if len(sources) == len(packages) {
+			return strings.Join(sources, ","), nil
+		}
+
+		jsonValue, err := json.Marshal(packages)
+		return string(jsonValue), err
+	case objectSetting:
+		value := configViper.Get(key)
+		if value == nil {
+			value = map[string]interface{}{}
+		}
+
+		if keepCaseValue, ok := getKeepCaseValues(configViper.ConfigFileUsed())[key]; ok {
+			value = keepCaseValue
+		}
+
+		jsonValue, err := json.Marshal(s.redactSetting(key, value))
+		return string(jsonValue), err
 	}
 
-	return s.global.GetString(args[0]), nil
+	return s.redactSetting(key, configViper.GetString(key)).(string), nil
 }
 
-// ListSettings Lists all settings for the config command
+// ListSettings Lists all settings for the config command along with the source of each effective value
 func (s *Settings) ListSettings() {
 
 	t := table.New(os.Stdout)
 
-	t.SetHeaders("Setting", "Global Value", "Local Value")
+	t.SetHeaders("Setting", "Global Value", "Local Value", "Source")
 
-	t.AddRow("admin.email", console.Bold(s.global.GetString("admin.email")))
-	t.AddRow("admin.password", console.Bold(s.global.GetString("admin.password")))
-	t.AddRow("admnin.username", console.Bold(s.global.GetString("admin.username")))
-	t.AddRow("local", console.Bold(s.global.GetString("local")), console.Bold(s.local.GetString("local")))
-	t.AddRow("php", console.Bold(s.global.GetString("php")), console.Bold(s.local.GetString("php")))
-	t.AddRow("type", console.Bold(s.global.GetString("type")), console.Bold(s.local.GetString("type")))
-	t.AddRow("xdebug", console.Bold(s.global.GetString("xdebug")), console.Bold(s.local.GetString("xdebug")))
-	t.AddRow("phpmyadmin", console.Bold(s.global.GetString("phpmyadmin")), console.Bold(s.local.GetString("phpmyadmin")))
+	t.AddRow("admin.email", console.Bold(s.global.GetString("admin.email")), "", s.getSettingSource("admin.email"))
+	t.AddRow("admin.password", console.Bold(s.RedactSecret(s.global.GetString("admin.password"))), "", s.getSettingSource("admin.password"))
+	t.AddRow("admin.random_password", console.Bold(s.global.GetString("admin.random_password")), console.Bold(s.local.GetString("admin.random_password")), s.getSettingSource("admin.random_password"))
+	t.AddRow("admin.username", console.Bold(s.global.GetString("admin.username")), "", s.getSettingSource("admin.username"))
+	t.AddRow("local", console.Bold(s.global.GetString("local")), console.Bold(s.local.GetString("local")), s.getSettingSource("local"))
+	t.AddRow("lan", console.Bold(s.global.GetString("lan")), console.Bold(s.local.GetString("lan")), s.getSettingSource("lan"))
+	t.AddRow("lan_hostname", console.Bold(s.global.GetString("lan_hostname")), "", s.getSettingSource("lan_hostname"))
+	t.AddRow("ca.cert", console.Bold(s.global.GetString("ca.cert")), "", s.getSettingSource("ca.cert"))
+	t.AddRow("ca.key", console.Bold(s.global.GetString("ca.key")), "", s.getSettingSource("ca.key"))
+	t.AddRow("ca.domains", console.Bold(strings.Join(s.global.GetStringSlice("ca.domains"), ",")), "", s.getSettingSource("ca.domains"))
+	t.AddRow("ca.mkcert", console.Bold(s.global.GetString("ca.mkcert")), "", s.getSettingSource("ca.mkcert"))
+	t.AddRow("ca.key_type", console.Bold(s.global.GetString("ca.key_type")), "", s.getSettingSource("ca.key_type"))
+	t.AddRow("ssl", "", console.Bold(s.local.GetString("ssl")), s.getSettingSource("ssl"))
+	t.AddRow("php", console.Bold(s.global.GetString("php")), console.Bold(s.local.GetString("php")), s.getSettingSource("php"))
 
-	boldPlugins := []string{}
+	globalPHPIni, _ := s.GetSetting("php_ini", false)
+	localPHPIni, _ := s.GetSetting("php_ini", true)
 
-	for _, plugin := range s.Plugins {
-		boldPlugins = append(boldPlugins, console.Bold(plugin))
+	t.AddRow("php_ini", console.Bold(globalPHPIni), console.Bold(localPHPIni), s.getSettingSource("php_ini"))
+
+	t.AddRow("wordpress", console.Bold(s.global.GetString("wordpress")), console.Bold(s.local.GetString("wordpress")), s.getSettingSource("wordpress"))
+	t.AddRow("type", console.Bold(s.global.GetString("type")), console.Bold(s.local.GetString("type")), s.getSettingSource("type"))
+	t.AddRow("xdebug", console.Bold(s.global.GetString("xdebug")), console.Bold(s.local.GetString("xdebug")), s.getSettingSource("xdebug"))
+	t.AddRow("phpmyadmin", console.Bold(s.global.GetString("phpmyadmin")), console.Bold(s.local.GetString("phpmyadmin")), s.getSettingSource("phpmyadmin"))
+
+	for _, name := range ImageNames {
+		key := fmt.Sprintf("images.%s", name)
+		t.AddRow(key, console.Bold(s.global.GetString(key)), console.Bold(s.local.GetString(key)), s.getSettingSource(key))
 	}
 
-	plugins := console.Bold(strings.Join(boldPlugins, "\n"))
+	// Show the plugins and themes from the site's config file as the environment may have replaced s.Plugins and s.Themes
+	for _, key := range []string{"plugins", "themes"} {
 
-	t.AddRow("plugins", "", plugins)
+		boldPackages := []string{}
+
+		for _, description := range describePackages(getPackages(s.local.Get(key), isActiveByDefault(key)), isActiveByDefault(key)) {
+			boldPackages = append(boldPackages, console.Bold(description))
+		}
+
+		t.AddRow(key, "", strings.Join(boldPackages, "\n"), s.getSettingSource(key))
+	}
+
+	constants, err := s.GetSetting("constants", true)
+	if err == nil {
+		t.AddRow("constants", "", console.Bold(constants), s.getSettingSource("constants"))
+	}
+
+	options, err := s.GetSetting("options", true)
+	if err == nil {
+		t.AddRow("options", "", console.Bold(options), s.getSettingSource("options"))
+	}
 
 	t.Render()
 }
 
-// SetGlobalSetting Sets a global setting for the "config" command
-func (s *Settings) SetGlobalSetting(md *cobra.Command, args []string) error {
+// SetSetting Validates and saves a global setting, or a setting for the current site when local is true, for the "config" command
+func (s *Settings) SetSetting(key, value string, local bool) error {
+
+	schema, configViper, err := s.getConfigSetting(key, local)
+	if err != nil {
+		return err
+	}
 
-	if !s.global.IsSet(args[0]) {
-		return fmt.Errorf("invalid setting. Please enter a valid key to set")
+	parsedValue, err := schema.parse(value)
+	if err != nil {
+		return err
 	}
 
-	validate := validator.New()
-	var err error
+	err = schema.check(parsedValue)
+	if err != nil {
+		return err
+	}
 
-	switch args[0] {
-	case "local", "xdebug":
-		err = validate.Var(args[1], "boolean")
+	// Basic auth passwords are hashed before they are written so they aren't committed with the site
+	if key == "middlewares" {
+		_, err = hashBasicAuthPasswords(parsedValue.(map[string]interface{}))
 		if err != nil {
 			return err
 		}
-		boolVal, err := strconv.ParseBool(args[1])
+	}
+
+	configViper.Set(key, parsedValue)
+
+	return editConfigFile(s.GetConfigFile(local), map[string]interface{}{key: parsedValue}, []string{})
+}
+
+// UnsetSetting Removes a global setting, or a setting for the current site when local is true, so that its default applies again
+func (s *Settings) UnsetSetting(key string, local bool) error {
+
+	_, _, err := s.getConfigSetting(key, local)
+	if err != nil {
+		return err
+	}
+
+	return editConfigFile(s.GetConfigFile(local), map[string]interface{}{}, []string{key})
+}
+
+// EditConfig Opens the global config, or the current site's config when local is true, in the user's editor and validates it once they are done. Invalid changes are discarded unless the user chooses to keep editing.
+func (s *Settings) EditConfig(local bool) (string, error) {
+
+	configFile := s.GetConfigFile(local)
+	scope := globalScope
+	permissions := configPermissions
+
+	if local {
+		scope = localScope
+		permissions = projectConfigPermissions
+	}
+
+	original, err := os.ReadFile(configFile)
+	if err != nil && !os.IsNotExist(err) {
+		return configFile, err
+	}
+
+	existed := err == nil
+
+	if !existed {
+		err = os.WriteFile(configFile, []byte(fmt.Sprintf("{\n  \"%s\": %d\n}\n", versionKey, configVersion)), permissions)
 		if err != nil {
-			return err
+			return configFile, err
 		}
-		s.global.Set(args[0], boolVal)
-		return s.global.WriteConfig()
-	case "php":
-		if !isValidString(args[1], validPHPVersions) {
-			err = fmt.Errorf("please choose a valid php version")
+	}
+
+	for {
+
+		err = openEditor(configFile)
+		if err != nil {
+			return configFile, err
 		}
-	case "type":
-		if !isValidString(args[1], validTypes) {
-			err = fmt.Errorf("please choose a valid project type")
+
+		err = validateConfigFile(configFile, scope)
+		if err == nil {
+			return configFile, nil
 		}
-	case "admin.email":
-		err = validate.Var(args[1], "email")
-	case "admin.password":
-		err = validate.Var(args[1], "alphanumunicode")
-	case "admin.username":
-		err = validate.Var(args[1], "alpha")
-	default:
-		err = validate.Var(args[1], "boolean")
+
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+
+		if !console.PromptConfirm("Would you like to keep editing?", true) {
+			break
+		}
+	}
+
+	// Put back what was there before so an invalid file never stops Kana from working
+	if !existed {
+		err = os.Remove(configFile)
+	} else {
+		err = os.WriteFile(configFile, original, permissions)
 	}
 
 	if err != nil {
-		return err
+		return configFile, err
+	}
+
+	return configFile, fmt.Errorf("your changes have been discarded as the config was invalid")
+}
+
+// getConfigSetting Returns the schema for a key along with the config it should be read from, making sure the key can be used there
+func (s *Settings) getConfigSetting(key string, local bool) (setting, *viper.Viper, error) {
+
+	schema, ok := getSetting(key)
+
+	if local {
+
+		if !ok || schema.scope&localScope == 0 {
+			return schema, s.local, fmt.Errorf("invalid setting. Please enter a valid site setting")
+		}
+
+		return schema, s.local, nil
 	}
 
-	s.global.Set(args[0], args[1])
+	if !ok || schema.scope&globalScope == 0 {
+		return schema, s.global, fmt.Errorf("invalid setting. Please enter a valid global setting")
+	}
+
+	return schema, s.global, nil
+}
+
+// GetConfigFile Returns the path to the global config file, or the current site's config file when local is true
+func (s *Settings) GetConfigFile(local bool) string {
+
+	if local {
+		return s.local.ConfigFileUsed()
+	}
+
+	return s.global.ConfigFileUsed()
+}
+
+// setNestedValue Sets a dotted key such as "images.wordpress" in a map of settings
+func setNestedValue(settings map[string]interface{}, key string, value interface{}) {
+
+	parent, name, found := strings.Cut(key, ".")
+	if !found {
+		settings[key] = value
+		return
+	}
+
+	child, ok := settings[parent].(map[string]interface{})
+	if !ok {
+		child = map[string]interface{}{}
+		settings[parent] = child
+	}
+
+	setNestedValue(child, name, value)
+}
+
+// deleteNestedValue Removes a dotted key such as "images.wordpress" from a map of settings, along with any parents it leaves empty
+func deleteNestedValue(settings map[string]interface{}, key string) {
+
+	parent, name, found := strings.Cut(key, ".")
+	if !found {
+		delete(settings, key)
+		return
+	}
+
+	child, ok := settings[parent].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	deleteNestedValue(child, name)
+
+	if len(child) == 0 {
+		delete(settings, parent)
+	}
+}
+
+// openEditor Opens a file in the user's preferred editor and waits for it to be closed
+func openEditor(file string) error {
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// Editors such as "code --wait" include their own arguments
+	args := append(strings.Fields(editor), file)
+
+	editorCmd := exec.Command(args[0], args[1:]...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
 
-	return s.global.WriteConfig()
+	return editorCmd.Run()
 }
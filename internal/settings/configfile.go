@@ -0,0 +1,337 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config files created in a project are committed along with it so they can be read by anyone like the project's other files.
+// The global config, which can hold the admin password, is kept to configPermissions when it is loaded.
+const projectConfigPermissions os.FileMode = 0644
+
+// editConfigFile Sets and removes dotted keys such as "images.wordpress" in a config file, leaving the rest of the file alone.
+// YAML files are edited in place so their comments and the order of their keys are kept. JSON and TOML files are rewritten,
+// which loses any comments in a TOML file so Kana warns when it removes them.
+// Files are read and written without viper so the keys of objects such as header names keep their case. Existing files keep their permissions.
+func editConfigFile(configFile string, values map[string]interface{}, unset []string) error {
+
+	contents, err := os.ReadFile(configFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	permissions := projectConfigPermissions
+
+	if configInfo, err := os.Stat(configFile); err == nil {
+		permissions = configInfo.Mode().Perm()
+	}
+
+	removedComments := false
+
+	switch strings.TrimPrefix(filepath.Ext(configFile), ".") {
+	case "yaml", "yml":
+		contents, err = editYAML(contents, values, unset)
+		if err != nil {
+			return fmt.Errorf("unable to update %s: %s", configFile, err)
+		}
+	default:
+		settings, err := readConfigFile(configFile)
+		if err != nil {
+			return err
+		}
+
+		for key, value := range values {
+			setNestedValue(settings, key, value)
+		}
+
+		for _, key := range unset {
+			deleteNestedValue(settings, key)
+		}
+
+		// Formatted the same way viper writes them
+		if strings.HasSuffix(configFile, ".toml") {
+			removedComments = hasTOMLComments(contents)
+			contents, err = toml.Marshal(settings)
+		} else {
+			contents, err = json.MarshalIndent(settings, "", "  ")
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.WriteFile(configFile, contents, permissions)
+	if err != nil {
+		return err
+	}
+
+	if removedComments {
+		console.Warn(fmt.Sprintf("Kana can only keep comments in YAML config files so the comments in %s have been removed.", configFile))
+	}
+
+	return nil
+}
+
+// readConfigFile Reads the settings in a config file without viper, which lowercases every key, so values such as header names keep their case
+func readConfigFile(configFile string) (map[string]interface{}, error) {
+
+	settings := map[string]interface{}{}
+
+	contents, err := os.ReadFile(configFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return settings, nil
+		}
+
+		return settings, err
+	}
+
+	if len(bytes.TrimSpace(contents)) == 0 {
+		return settings, nil
+	}
+
+	switch strings.TrimPrefix(filepath.Ext(configFile), ".") {
+	case "yaml", "yml":
+		err = yaml.Unmarshal(contents, &settings)
+	case "toml":
+		err = toml.Unmarshal(contents, &settings)
+	default:
+		err = json.Unmarshal(contents, &settings)
+	}
+
+	if err != nil {
+		return settings, fmt.Errorf("%s is invalid: %s", configFile, err)
+	}
+
+	return settings, nil
+}
+
+// getKeepCaseValues Returns the settings in a config file that have to keep the case of their keys, such as header names, as they were written. Unreadable files are reported when the config is validated.
+func getKeepCaseValues(configFile string) map[string]interface{} {
+
+	values := map[string]interface{}{}
+
+	settings, err := readConfigFile(configFile)
+	if err != nil {
+		return values
+	}
+
+	for _, schema := range settingsSchema {
+
+		if !schema.keepCase {
+			continue
+		}
+
+		// Viper matches keys regardless of case so this does as well
+		for name, value := range settings {
+			if strings.EqualFold(name, schema.key) {
+				values[schema.key] = value
+			}
+		}
+	}
+
+	return values
+}
+
+// editYAML Sets and removes dotted keys in a YAML document, keeping its comments and the order of its keys
+func editYAML(contents []byte, values map[string]interface{}, unset []string) ([]byte, error) {
+
+	document := yaml.Node{}
+
+	err := yaml.Unmarshal(contents, &document)
+	if err != nil {
+		return contents, err
+	}
+
+	// Empty files, or files holding only comments, don't have a mapping to add the keys to yet
+	if document.Kind != yaml.DocumentNode {
+		document = yaml.Node{Kind: yaml.DocumentNode, HeadComment: document.HeadComment}
+	}
+
+	if len(document.Content) == 0 {
+		document.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return contents, fmt.Errorf("the file should hold a mapping of settings")
+	}
+
+	// Keys are added in the same order every time
+	keys := []string{}
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+
+		value := &yaml.Node{}
+
+		err = value.Encode(values[key])
+		if err != nil {
+			return contents, err
+		}
+
+		setYAMLValue(root, strings.Split(key, "."), value)
+	}
+
+	for _, key := range unset {
+		deleteYAMLValue(root, strings.Split(key, "."))
+	}
+
+	var buffer bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+
+	err = encoder.Encode(&document)
+	if err != nil {
+		return contents, err
+	}
+
+	err = encoder.Close()
+
+	return buffer.Bytes(), err
+}
+
+// setYAMLValue Sets the value of a key, given as its path through nested mappings, keeping any comments on the value it replaces
+func setYAMLValue(mapping *yaml.Node, keyPath []string, value *yaml.Node) {
+
+	// Viper lowercases keys so they are matched regardless of case
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+
+		if !strings.EqualFold(mapping.Content[i].Value, keyPath[0]) {
+			continue
+		}
+
+		current := mapping.Content[i+1]
+
+		if len(keyPath) == 1 {
+			value.HeadComment = current.HeadComment
+			value.LineComment = current.LineComment
+			value.FootComment = current.FootComment
+
+			// Items of a list keep their comments when they are changed in place, such as when basic auth passwords are hashed
+			if value.Kind == yaml.SequenceNode && current.Kind == yaml.SequenceNode {
+				for j := 0; j < len(value.Content) && j < len(current.Content); j++ {
+					value.Content[j].HeadComment = current.Content[j].HeadComment
+					value.Content[j].LineComment = current.Content[j].LineComment
+					value.Content[j].FootComment = current.Content[j].FootComment
+				}
+			}
+
+			// A comment on the same line as a value moves to the key when the value is replaced by a list or object so it stays on that line
+			if value.Kind != yaml.ScalarNode && value.Style&yaml.FlowStyle == 0 && mapping.Content[i].LineComment == "" {
+				mapping.Content[i].LineComment = value.LineComment
+				value.LineComment = ""
+			}
+
+			mapping.Content[i+1] = value
+
+			return
+		}
+
+		if current.Kind != yaml.MappingNode {
+			current = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", LineComment: current.LineComment}
+			mapping.Content[i+1] = current
+		}
+
+		setYAMLValue(current, keyPath[1:], value)
+
+		return
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: keyPath[0]}
+
+	if len(keyPath) == 1 {
+		mapping.Content = append(mapping.Content, key, value)
+		return
+	}
+
+	child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	mapping.Content = append(mapping.Content, key, child)
+
+	setYAMLValue(child, keyPath[1:], value)
+}
+
+// deleteYAMLValue Removes a key, given as its path through nested mappings, along with any parents it leaves empty
+func deleteYAMLValue(mapping *yaml.Node, keyPath []string) {
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+
+		if !strings.EqualFold(mapping.Content[i].Value, keyPath[0]) {
+			continue
+		}
+
+		child := mapping.Content[i+1]
+
+		if len(keyPath) > 1 {
+
+			if child.Kind != yaml.MappingNode {
+				return
+			}
+
+			deleteYAMLValue(child, keyPath[1:])
+
+			if len(child.Content) > 0 {
+				return
+			}
+		}
+
+		mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+
+		return
+	}
+}
+
+// hasTOMLComments Checks if a TOML file has any comments, skipping over anything that looks like a comment inside a string
+func hasTOMLComments(contents []byte) bool {
+
+	text := string(contents)
+
+	for i := 0; i < len(text); i++ {
+
+		switch {
+		case text[i] == '#':
+			return true
+		case strings.HasPrefix(text[i:], `"""`), strings.HasPrefix(text[i:], "'''"):
+			i = skipTOMLString(text, i, text[i:i+3])
+		case text[i] == '"', text[i] == '\'':
+			i = skipTOMLString(text, i, text[i:i+1])
+		}
+	}
+
+	return false
+}
+
+// skipTOMLString Returns the index of the last character of the string starting at start. Only basic strings, which use double quotes, have escapes.
+func skipTOMLString(text string, start int, delimiter string) int {
+
+	for i := start + len(delimiter); i < len(text); i++ {
+
+		if text[i] == '\\' && delimiter[0] == '"' {
+			i++
+			continue
+		}
+
+		if strings.HasPrefix(text[i:], delimiter) {
+			return i + len(delimiter) - 1
+		}
+	}
+
+	return len(text)
+}
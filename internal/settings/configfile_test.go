@@ -0,0 +1,201 @@
+package settings
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name, contents string) string {
+
+	configFile := path.Join(t.TempDir(), name)
+
+	err := os.WriteFile(configFile, []byte(contents), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return configFile
+}
+
+func readTestFile(t *testing.T, configFile string) string {
+
+	contents, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(contents)
+}
+
+func TestEditYAMLKeepsComments(t *testing.T) {
+
+	configFile := writeTestFile(t, ".kana.yaml", `# Settings for the team's site
+php: "8.1" # Matches production
+# Plugins every developer needs
+plugins:
+  - akismet
+  - query-monitor
+xdebug: false
+`)
+
+	err := editConfigFile(configFile, map[string]interface{}{"php": "8.2"}, []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := readTestFile(t, configFile)
+
+	for _, expected := range []string{
+		"# Settings for the team's site",
+		`php: "8.2" # Matches production`,
+		"# Plugins every developer needs",
+		"  - query-monitor",
+	} {
+		if !strings.Contains(contents, expected) {
+			t.Errorf("Expected the file to contain %q; got:\n%s", expected, contents)
+		}
+	}
+
+	if strings.Index(contents, "php:") > strings.Index(contents, "plugins:") || strings.Index(contents, "plugins:") > strings.Index(contents, "xdebug:") {
+		t.Errorf("Expected the keys to keep their order; got:\n%s", contents)
+	}
+}
+
+func TestEditYAMLNestedKeys(t *testing.T) {
+
+	configFile := writeTestFile(t, "kana.yaml", `admin:
+  # Used to log in to every site
+  username: admin
+  email: admin@sites.kana.li
+`)
+
+	err := editConfigFile(configFile, map[string]interface{}{"images.wordpress": "wordpress:6.1", "admin.username": "kana"}, []string{"admin.email"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `admin:
+  # Used to log in to every site
+  username: kana
+images:
+  wordpress: wordpress:6.1
+`
+
+	if contents := readTestFile(t, configFile); contents != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, contents)
+	}
+}
+
+func TestEditYAMLReplaceScalarWithList(t *testing.T) {
+
+	configFile := writeTestFile(t, ".kana.yaml", "plugins: \"akismet, query-monitor\" # Needed by every developer\nphp: \"8.1\"\n")
+
+	err := editConfigFile(configFile, map[string]interface{}{"plugins": []interface{}{"akismet", "query-monitor"}}, []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `plugins: # Needed by every developer
+  - akismet
+  - query-monitor
+php: "8.1"
+`
+
+	if contents := readTestFile(t, configFile); contents != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, contents)
+	}
+}
+
+func TestEditYAMLListKeepsItemComments(t *testing.T) {
+
+	configFile := writeTestFile(t, ".kana.yaml", "plugins:\n  # Needed by every developer\n  - akismet # Spam protection\n  - query-monitor\n")
+
+	err := editConfigFile(configFile, map[string]interface{}{"plugins": []interface{}{"akismet@5.0.2", "query-monitor@3.11.1"}}, []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `plugins:
+  # Needed by every developer
+  - akismet@5.0.2 # Spam protection
+  - query-monitor@3.11.1
+`
+
+	if contents := readTestFile(t, configFile); contents != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, contents)
+	}
+}
+
+func TestEditTOMLWithComments(t *testing.T) {
+
+	configFile := writeTestFile(t, ".kana.toml", "# Settings for the team's site\nphp = \"8.1\"\nxdebug = true\n")
+
+	err := editConfigFile(configFile, map[string]interface{}{"php": "8.2"}, []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := readConfigFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{"php": "8.2", "xdebug": true}
+
+	if !reflect.DeepEqual(settings, expected) {
+		t.Errorf("Expected the TOML file to be updated to %v; got %v", expected, settings)
+	}
+}
+
+func TestHasTOMLComments(t *testing.T) {
+
+	tests := []struct {
+		contents string
+		expected bool
+	}{
+		{"php = \"8.1\"\n", false},
+		{"php = \"8.1\" # Matches production\n", true},
+		{"[options]\nblogname = \"Site #1\"\n", false},
+		{"[options]\nblogname = 'Site #1'\n", false},
+		{"[options]\nblogdescription = \"\"\"\nA \\\"quoted\\\" # description\n\"\"\"\n", false},
+		{"[options]\nblogname = \"Site \\\"#1\\\"\"\n# Another comment\n", true},
+	}
+
+	for _, test := range tests {
+		if hasTOMLComments([]byte(test.contents)) != test.expected {
+			t.Errorf("Expected hasTOMLComments to be %t for:\n%s", test.expected, test.contents)
+		}
+	}
+}
+
+func TestEditConfigFilePermissions(t *testing.T) {
+
+	configFile := writeTestFile(t, ".kana.json", `{"php": "8.1"}`)
+
+	err := os.Chmod(configFile, 0664)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newFile := path.Join(t.TempDir(), ".kana.yaml")
+
+	for file, expected := range map[string]os.FileMode{configFile: 0664, newFile: projectConfigPermissions} {
+
+		err = editConfigFile(file, map[string]interface{}{"php": "8.2"}, []string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fileInfo, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if fileInfo.Mode().Perm() != expected {
+			t.Errorf("Expected %s to have the permissions %v; got %v", file, expected, fileInfo.Mode().Perm())
+		}
+	}
+}
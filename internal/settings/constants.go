@@ -0,0 +1,78 @@
+package settings
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// PHP constant names start with a letter or underscore followed by letters, numbers and underscores
+var constantNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// The key in link.json holding the constants Kana has added to wp-config.php
+var appliedConstantsKey = "constants"
+
+// GetAppliedConstants Returns the constants Kana added to the site's wp-config.php the last time it started
+func (s *Settings) GetAppliedConstants() ([]string, error) {
+
+	linkConfig := viper.New()
+	linkConfig.SetConfigFile(path.Join(s.SiteDirectory, "link.json"))
+
+	err := linkConfig.ReadInConfig()
+	if err != nil {
+		return []string{}, err
+	}
+
+	return linkConfig.GetStringSlice(appliedConstantsKey), nil
+}
+
+// SaveAppliedConstants Records the constants Kana has added to the site's wp-config.php so they can be removed if they are removed from the config
+func (s *Settings) SaveAppliedConstants(names []string) error {
+
+	sort.Strings(names)
+
+	return editConfigFile(path.Join(s.SiteDirectory, "link.json"), map[string]interface{}{appliedConstantsKey: names}, []string{})
+}
+
+// getConstants Returns the constants from a config. PHP constants are case sensitive so their names are kept as they were written.
+func getConstants(values map[string]interface{}) map[string]interface{} {
+
+	constants := map[string]interface{}{}
+
+	for name, value := range values {
+		constants[name] = value
+	}
+
+	return constants
+}
+
+// validateConstants Checks that every constant has a valid name and a value that can be written to wp-config.php
+func validateConstants(value interface{}) error {
+
+	problems := []string{}
+
+	for name, constantValue := range getConstants(value.(map[string]interface{})) {
+
+		if !constantNamePattern.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("\"%s\" is not a valid constant name", name))
+			continue
+		}
+
+		switch constantValue.(type) {
+		case bool, string, int, int64, float64:
+		default:
+			problems = append(problems, fmt.Sprintf("%s must be a string, number or boolean but got %s", name, describeValue(constantValue)))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
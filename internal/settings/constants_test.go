@@ -0,0 +1,58 @@
+package settings
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestValidateConstants(t *testing.T) {
+
+	tests := []struct {
+		constants map[string]interface{}
+		valid     bool
+	}{
+		{map[string]interface{}{"WP_DEBUG": true, "WP_MEMORY_LIMIT": "256M", "WP_POST_REVISIONS": 5}, true},
+		{map[string]interface{}{"My_Plugin_Debug": true, "_private": 1.5}, true},
+		{map[string]interface{}{"1_DEBUG": true}, false},
+		{map[string]interface{}{"WP-DEBUG": true}, false},
+		{map[string]interface{}{"WP_DEBUG": []interface{}{true}}, false},
+	}
+
+	for _, test := range tests {
+
+		err := validateConstants(test.constants)
+		if (err == nil) != test.valid {
+			t.Errorf("Expected %v to be valid: %t; got %v", test.constants, test.valid, err)
+		}
+	}
+}
+
+func TestConstantsKeepCase(t *testing.T) {
+
+	configFile := writeTestFile(t, ".kana.yaml", "constants:\n  WP_DEBUG: true\n  My_Plugin_Debug: false\n")
+
+	config := viper.New()
+	config.SetConfigFile(configFile)
+
+	err := config.ReadInConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings := Settings{
+		Images: map[string]string{},
+		layers: map[layerRank]settingLayer{
+			localLayer: getFileLayer("local", config, localScope),
+		},
+	}
+
+	settings.applySettings(layerCount)
+
+	expected := map[string]interface{}{"WP_DEBUG": true, "My_Plugin_Debug": false}
+
+	if !reflect.DeepEqual(settings.Constants, expected) {
+		t.Errorf("Expected the constants to be %v; got %v", expected, settings.Constants)
+	}
+}
@@ -0,0 +1,146 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+const envPrefix = "KANA"
+
+// getEnvName Returns the environment variable that overrides a setting, such as KANA_ADMIN_PASSWORD for admin.password
+func getEnvName(key string) string {
+
+	return fmt.Sprintf("%s_%s", envPrefix, strings.ToUpper(strings.ReplaceAll(key, ".", "_")))
+}
+
+// getEnvironmentSettings Reads every KANA_* environment variable that matches a setting. Returns the valid values by key along with a description of any invalid ones.
+func getEnvironmentSettings() (map[string]interface{}, []string) {
+
+	values := map[string]interface{}{}
+	problems := []string{}
+
+	for _, schema := range settingsSchema {
+
+		envName := getEnvName(schema.key)
+
+		// Empty variables are ignored so that CI systems can declare them without a value
+		envValue := os.Getenv(envName)
+		if envValue == "" {
+			continue
+		}
+
+		value, err := schema.parse(envValue)
+		if err == nil {
+			err = schema.check(value)
+		}
+
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", envName, err))
+			continue
+		}
+
+		values[schema.key] = value
+	}
+
+	sort.Strings(problems)
+
+	return values, problems
+}
+
+// validateEnvironment Checks any KANA_* environment variables against the schema
+func validateEnvironment() error {
+
+	_, problems := getEnvironmentSettings()
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("invalid environment variables:\n  %s", strings.Join(problems, "\n  "))
+}
+
+// WarnConfigProblems Warns about invalid KANA_* environment variables, which are ignored, and config files that need to be migrated for commands that don't validate the config
+func (s *Settings) WarnConfigProblems() {
+
+	_, problems := getEnvironmentSettings()
+	if len(problems) > 0 {
+		console.Warn(fmt.Sprintf("Ignoring invalid environment variables:\n  %s", strings.Join(problems, "\n  ")))
+	}
+
+	for _, problem := range s.getMigrationProblems() {
+		console.Warn(problem)
+	}
+}
+
+// setValue Sets the field for a setting from a value that has already been checked against the schema
+func (s *Settings) setValue(key string, value interface{}) error {
+
+	switch key {
+	case "admin.email":
+		s.AdminEmail = value.(string)
+	case "admin.password":
+		s.AdminPassword = value.(string)
+	case "admin.random_password":
+		s.AdminRandomPassword = value.(bool)
+	case "admin.username":
+		s.AdminUsername = value.(string)
+	case "ca.cert":
+		s.CACert = value.(string)
+	case "ca.domains":
+		s.CADomains = []string{}
+
+		for _, domain := range value.([]interface{}) {
+			s.CADomains = append(s.CADomains, domain.(string))
+		}
+	case "ca.key":
+		s.CAKey = value.(string)
+	case "ca.key_type":
+		s.CAKeyType = value.(string)
+	case "ca.mkcert":
+		s.CAMkcert = value.(bool)
+	case "constants":
+		s.Constants = getConstants(value.(map[string]interface{}))
+	case "lan":
+		s.Lan = value.(bool)
+	case "lan_hostname":
+		s.LanHostname = value.(string)
+	case "local":
+		s.Local = value.(bool)
+	case "middlewares":
+		s.Middlewares = Middlewares{}
+		return mapstructure.Decode(value, &s.Middlewares)
+	case "options":
+		s.Options = value.(map[string]interface{})
+	case "php":
+		s.PHP = value.(string)
+	case "php_ini":
+		s.mergePHPIni(value.(map[string]interface{}))
+	case "phpmyadmin":
+		s.PhpMyAdmin = value.(bool)
+	case "plugins":
+		s.Plugins = getPackages(value, true)
+	case "presets":
+		s.presets = value.(map[string]interface{})
+	case "ssl":
+		s.SSL = value.(bool)
+	case "themes":
+		s.Themes = getPackages(value, false)
+	case "type":
+		s.Type = value.(string)
+	case "wordpress":
+		s.WordPress = value.(string)
+	case "xdebug":
+		s.Xdebug = value.(bool)
+	default:
+		if strings.HasPrefix(key, "images.") {
+			s.Images[strings.TrimPrefix(key, "images.")] = value.(string)
+		}
+	}
+
+	return nil
+}
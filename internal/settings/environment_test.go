@@ -0,0 +1,81 @@
+package settings
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetEnvironmentSettings(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		variables map[string]string
+		values    map[string]interface{}
+		problems  []string
+	}{
+		{
+			"bool",
+			map[string]string{"KANA_XDEBUG": "true", "KANA_ADMIN_RANDOM_PASSWORD": "0"},
+			map[string]interface{}{"xdebug": true, "admin.random_password": false},
+			[]string{},
+		},
+		{
+			"list",
+			map[string]string{"KANA_CA_DOMAINS": "home.lan, dev.test,"},
+			map[string]interface{}{"ca.domains": []interface{}{"home.lan", "dev.test"}},
+			[]string{},
+		},
+		{
+			"packages",
+			map[string]string{"KANA_PLUGINS": "akismet,query-monitor@3.11.1", "KANA_THEMES": `[{"source": "twentytwentythree", "active": true}]`},
+			map[string]interface{}{
+				"plugins": []interface{}{"akismet", "query-monitor@3.11.1"},
+				"themes":  []interface{}{map[string]interface{}{"source": "twentytwentythree", "active": true}},
+			},
+			[]string{},
+		},
+		{
+			"object",
+			map[string]string{"KANA_OPTIONS": `{"blogname": "Kana", "posts_per_page": 5}`},
+			map[string]interface{}{"options": map[string]interface{}{"blogname": "Kana", "posts_per_page": float64(5)}},
+			[]string{},
+		},
+		{
+			"empty",
+			map[string]string{"KANA_PHP": ""},
+			map[string]interface{}{},
+			[]string{},
+		},
+		{
+			"invalid values",
+			map[string]string{"KANA_XDEBUG": "yes", "KANA_OPTIONS": "blogname=Kana", "KANA_PLUGINS": "[akismet", "KANA_PHP": "5.6"},
+			map[string]interface{}{},
+			[]string{
+				"KANA_OPTIONS: options must be a JSON object",
+				`KANA_PHP: "5.6" is not a valid PHP version. Please choose one of: 7.4, 8.0, 8.1, 8.2`,
+				"KANA_PLUGINS: plugins must be a comma separated list or a JSON array",
+				"KANA_XDEBUG: xdebug must be true or false",
+			},
+		},
+	}
+
+	for _, test := range tests {
+
+		t.Run(test.name, func(t *testing.T) {
+
+			for name, value := range test.variables {
+				t.Setenv(name, value)
+			}
+
+			values, problems := getEnvironmentSettings()
+
+			if !reflect.DeepEqual(values, test.values) {
+				t.Errorf("Expected the values %v; got %v", test.values, values)
+			}
+
+			if !reflect.DeepEqual(problems, test.problems) {
+				t.Errorf("Expected the problems %q; got %q", test.problems, problems)
+			}
+		})
+	}
+}
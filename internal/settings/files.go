@@ -1,12 +1,20 @@
 package settings
 
 import (
+	"bytes"
+	"crypto/x509"
 	_ "embed"
+	"encoding/pem"
+	"fmt"
 	"os"
-	"os/exec"
 	"path"
+	"path/filepath"
 	"runtime"
+	"strings"
+	"text/template"
+	"time"
 
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
 	"github.com/ChrisWiegman/kana-cli/pkg/minica"
 )
 
@@ -15,6 +23,14 @@ type File struct {
 	Permissions               os.FileMode
 }
 
+type tlsCertificate struct {
+	CertFile, KeyFile string
+}
+
+type templateData struct {
+	Certificates []tlsCertificate
+}
+
 //go:embed templates/dynamic.toml
 var DYNAMIC_TOML string
 
@@ -36,8 +52,8 @@ var configFiles = []File{
 	},
 }
 
-// EnsureSSLCerts Ensures SSL certificates have been generated and are where they need to be
-func (s *Settings) EnsureSSLCerts() error {
+// EnsureSSLCerts Ensures SSL certificates have been generated, are current and are where they need to be. Returns true if the site certificate was reissued.
+func (s *Settings) EnsureSSLCerts() (bool, error) {
 
 	createCert := false
 	certPath := path.Join(s.AppDirectory, "certs")
@@ -48,31 +64,224 @@ func (s *Settings) EnsureSSLCerts() error {
 		createCert = true
 	}
 
-	if createCert {
+	err = os.MkdirAll(certPath, 0750)
+	if err != nil {
+		return false, err
+	}
+
+	// Kana never creates, changes or trusts an external CA. We only sign with it.
+	if s.isExternalCA() {
 
-		err = os.MkdirAll(certPath, 0750)
+		caCert, caKey, err := s.getCAFiles()
 		if err != nil {
-			return err
+			return false, err
 		}
 
-		certInfo := minica.CertInfo{
-			CertDir:    certPath,
-			CertDomain: s.AppDomain,
-			RootKey:    s.RootKey,
-			RootCert:   s.RootCert,
-			SiteCert:   s.SiteCert,
-			SiteKey:    s.SiteKey,
+		for _, file := range []string{caCert, caKey} {
+			if _, err = os.Stat(file); err != nil {
+				return false, fmt.Errorf("unable to read the configured CA: %s", err)
+			}
 		}
 
-		err = minica.GenCerts(certInfo)
+		return s.ensureSignedCert(getWildcardDomains(s.AppDomain), s.SiteCert, s.SiteKey)
+	}
+
+	if !createCert {
+		return s.ensureSignedCert(getWildcardDomains(s.AppDomain), s.SiteCert, s.SiteKey)
+	}
+
+	// Any site certificate left without a root was signed by a CA that no longer exists
+	err = removeCertFiles(certPath, s.SiteCert, s.SiteKey)
+	if err != nil {
+		return false, err
+	}
+
+	certInfo, err := s.getCertInfo(certPath, getWildcardDomains(s.AppDomain), s.SiteCert, s.SiteKey)
+	if err != nil {
+		return false, err
+	}
+
+	err = minica.GenCerts(certInfo)
+	if err != nil {
+		return true, err
+	}
+
+	return true, s.installRootCert()
+}
+
+// EnsureLANCerts Ensures a certificate covering the given LAN domain has been signed by the Kana root CA. Returns true if it was reissued.
+func (s *Settings) EnsureLANCerts(lanDomain string) (bool, error) {
+
+	// The root only permits the LAN domain it was created for. Replacing it means trusting a new root on every device so that is left to the user.
+	if !s.isExternalCA() && s.checkNameConstraints(getWildcardDomains(lanDomain)) != nil {
+		return false, fmt.Errorf("the Kana root CA isn't allowed to sign certificates for %s, which happens when your LAN address or lan_hostname changes. Run `kana cert regenerate` to create a new root CA that covers it. Adding your LAN's domain to ca.domains before regenerating lets lan_hostname change within it", lanDomain)
+	}
+
+	renewed, err := s.ensureSignedCert(getWildcardDomains(lanDomain), s.LanCert, s.LanKey)
+	if err != nil || !renewed {
+		return renewed, err
+	}
+
+	// Regenerate dynamic.toml so Traefik picks up the new certificate
+	return renewed, s.EnsureStaticConfigFiles()
+}
+
+// EnsureSiteCerts Ensures the current site has its own certificate covering exactly the given hostnames. Returns true if it was reissued.
+func (s *Settings) EnsureSiteCerts(hostnames []string) (bool, error) {
+
+	err := os.MkdirAll(path.Join(s.AppDirectory, "certs", siteCertFolder), 0750)
+	if err != nil {
+		return false, err
+	}
+
+	certFile, keyFile := s.getSiteCertFiles()
+
+	renewed, err := s.ensureSignedCert(hostnames, certFile, keyFile)
+	if err != nil || !renewed {
+		return renewed, err
+	}
+
+	// Regenerate dynamic.toml so Traefik picks up the new certificate
+	return renewed, s.EnsureStaticConfigFiles()
+}
+
+// RemoveSiteCerts Removes the current site's own certificate and stops Traefik from loading it
+func (s *Settings) RemoveSiteCerts() error {
+
+	certFile, keyFile := s.getSiteCertFiles()
+
+	err := removeCertFiles(path.Join(s.AppDirectory, "certs"), certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	return s.EnsureStaticConfigFiles()
+}
+
+// installRootCert Adds the Kana root CA to the system trust stores on platforms we support
+func (s *Settings) installRootCert() error {
+
+	switch runtime.GOOS {
+	case "darwin":
+		_, err := s.TrustRootCert()
+		return err
+	case "linux":
+		// The site still works without a trusted root so only warn if we can't add it everywhere
+		_, err := s.TrustRootCert()
 		if err != nil {
-			return err
+			console.Warn(fmt.Sprintf("Unable to trust the Kana root CA: %s", err))
+		}
+	}
+
+	return nil
+}
+
+// ensureSignedCert Reissues a certificate for the domains from the existing root if it is missing, close to expiring or no longer matches
+func (s *Settings) ensureSignedCert(domains []string, certFile, keyFile string) (bool, error) {
+
+	certPath := path.Join(s.AppDirectory, "certs")
+
+	if s.isCertCurrent(domains, certFile, keyFile) {
+		return false, nil
+	}
+
+	err := s.checkNameConstraints(domains)
+	if err != nil {
+		return false, err
+	}
+
+	err = removeCertFiles(certPath, certFile, keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	certInfo, err := s.getCertInfo(certPath, domains, certFile, keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	return true, minica.GenCerts(certInfo)
+}
+
+// isCertCurrent Checks that a certificate and its key exist, cover all the domains, chain to the root and aren't close to expiring
+func (s *Settings) isCertCurrent(domains []string, certFile, keyFile string) bool {
+
+	certPath := path.Join(s.AppDirectory, "certs")
+
+	_, err := os.Stat(path.Join(certPath, keyFile))
+	if err != nil {
+		return false
+	}
+
+	cert, err := readCertFile(path.Join(certPath, certFile))
+	if err != nil {
+		return false
+	}
+
+	rootCert, err := s.GetRootCertPath()
+	if err != nil {
+		return false
+	}
+
+	root, err := readCertFile(rootCert)
+	if err != nil {
+		return false
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	for _, domain := range domains {
+
+		_, err = cert.Verify(x509.VerifyOptions{
+			DNSName:     domain,
+			Roots:       roots,
+			CurrentTime: time.Now().Add(certRenewalWindow),
+		})
+		if err != nil {
+			return false
 		}
+	}
+
+	return true
+}
+
+// getSiteCertFiles Returns the current site's certificate and key, relative to the certs folder
+func (s *Settings) getSiteCertFiles() (string, string) {
+
+	return path.Join(siteCertFolder, fmt.Sprintf("%s.pem", s.Name)),
+		path.Join(siteCertFolder, fmt.Sprintf("%s.key", s.Name))
+}
+
+// getWildcardDomains Returns the names a wildcard certificate for the domain is issued for
+func getWildcardDomains(domain string) []string {
+
+	return []string{fmt.Sprintf("*.%s", domain)}
+}
+
+// readCertFile Reads and parses a PEM encoded certificate
+func readCertFile(file string) (*x509.Certificate, error) {
+
+	certContents, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
 
-		// If we're on Mac try to add the cert to the system trust
-		if runtime.GOOS == "darwin" {
-			installCertCommand := exec.Command("sudo", "security", "add-trusted-cert", "-d", "-r", "trustRoot", "-k", "/Library/Keychains/System.keychain", rootCert)
-			return installCertCommand.Run()
+	block, _ := pem.Decode(certContents)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no certificate found in %s", file)
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// removeCertFiles Removes a certificate and its key if they exist
+func removeCertFiles(certPath, certFile, keyFile string) error {
+
+	for _, file := range []string{certFile, keyFile} {
+		err := os.Remove(path.Join(certPath, file))
+		if err != nil && !os.IsNotExist(err) {
+			return err
 		}
 	}
 
@@ -91,9 +300,19 @@ func (s *Settings) EnsureStaticConfigFiles() error {
 			return err
 		}
 
-		finalTemplate := []byte(file.Template)
+		tmpl, err := template.New(file.Name).Parse(file.Template)
+		if err != nil {
+			return err
+		}
+
+		var finalTemplate bytes.Buffer
 
-		err := os.WriteFile(destFile, finalTemplate, file.Permissions)
+		err = tmpl.Execute(&finalTemplate, s.getTemplateData())
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(destFile, finalTemplate.Bytes(), file.Permissions)
 		if err != nil {
 			return err
 		}
@@ -101,3 +320,47 @@ func (s *Settings) EnsureStaticConfigFiles() error {
 
 	return nil
 }
+
+// getTemplateData Gathers the values needed to render the application's config file templates
+func (s *Settings) getTemplateData() templateData {
+
+	data := templateData{
+		Certificates: []tlsCertificate{
+			{
+				CertFile: s.SiteCert,
+				KeyFile:  s.SiteKey,
+			},
+		},
+	}
+
+	// Only reference the LAN certificate once it has been generated so Traefik doesn't fail to load it
+	_, certErr := os.Stat(path.Join(s.AppDirectory, "certs", s.LanCert))
+	_, keyErr := os.Stat(path.Join(s.AppDirectory, "certs", s.LanKey))
+
+	if certErr == nil && keyErr == nil {
+		data.Certificates = append(data.Certificates, tlsCertificate{
+			CertFile: s.LanCert,
+			KeyFile:  s.LanKey,
+		})
+	}
+
+	// Every site's own certificate is listed so Traefik can serve the most specific one for each hostname
+	siteCerts, _ := filepath.Glob(path.Join(s.AppDirectory, "certs", siteCertFolder, "*.pem"))
+
+	for _, siteCert := range siteCerts {
+
+		name := strings.TrimSuffix(filepath.Base(siteCert), ".pem")
+		keyFile := path.Join(siteCertFolder, fmt.Sprintf("%s.key", name))
+
+		if _, err := os.Stat(path.Join(s.AppDirectory, "certs", keyFile)); err != nil {
+			continue
+		}
+
+		data.Certificates = append(data.Certificates, tlsCertificate{
+			CertFile: path.Join(siteCertFolder, filepath.Base(siteCert)),
+			KeyFile:  keyFile,
+		})
+	}
+
+	return data
+}
@@ -1,6 +1,8 @@
 package settings
 
 import (
+	"fmt"
+	"os"
 	"path"
 
 	"github.com/spf13/viper"
@@ -15,14 +17,19 @@ func (s *Settings) LoadGlobalSettings() error {
 	}
 
 	s.global = globalViperConfig
-	s.Xdebug = globalViperConfig.GetBool("xdebug")
-	s.PhpMyAdmin = globalViperConfig.GetBool("phpmyadmin")
-	s.Local = globalViperConfig.GetBool("local")
-	s.AdminEmail = globalViperConfig.GetString("admin.email")
-	s.AdminPassword = globalViperConfig.GetString("admin.password")
-	s.AdminUsername = globalViperConfig.GetString("admin.username")
-	s.PHP = globalViperConfig.GetString("php")
-	s.Type = globalViperConfig.GetString("type")
+	// Invalid environment variables are reported when the config is validated, or as a warning by the config and preset commands
+	s.environment, _ = getEnvironmentSettings()
+	s.presets = map[string]interface{}{}
+	s.Images = make(map[string]string)
+
+	// The preset, the site's config file and the start flags are added as their layers are loaded
+	s.layers = map[layerRank]settingLayer{
+		defaultLayer:     getDefaultLayer(),
+		globalLayer:      getFileLayer("global", globalViperConfig, globalScope),
+		environmentLayer: s.getEnvironmentLayer(),
+	}
+
+	s.applySettings(layerCount)
 
 	return err
 }
@@ -32,52 +39,43 @@ func (s *Settings) loadGlobalViper() (*viper.Viper, error) {
 
 	globalSettings := viper.New()
 
-	globalSettings.SetDefault("xdebug", xdebug)
-	globalSettings.SetDefault("phpmyadmin", phpmyadmin)
-	globalSettings.SetDefault("type", siteType)
-	globalSettings.SetDefault("local", local)
-	globalSettings.SetDefault("php", php)
-	globalSettings.SetDefault("admin.username", adminUsername)
-	globalSettings.SetDefault("admin.password", adminPassword)
-	globalSettings.SetDefault("admin.email", adminEmail)
+	globalSettings.SetDefault(versionKey, configVersion)
 
-	globalSettings.SetConfigName("kana")
-	globalSettings.SetConfigType("json")
-	globalSettings.AddConfigPath(path.Join(s.AppDirectory, "config"))
-
-	err := globalSettings.ReadInConfig()
-	if err != nil {
-		_, ok := err.(viper.ConfigFileNotFoundError)
-		if ok {
-			err = globalSettings.SafeWriteConfig()
-			if err != nil {
-				return globalSettings, err
-			}
-		} else {
-			return globalSettings, err
+	for key, value := range getDefaultSettings() {
+		if schema, _ := getSetting(key); schema.scope&globalScope != 0 {
+			globalSettings.SetDefault(key, value)
 		}
 	}
 
-	changeConfig := false
-
-	// Reset default "site" type if there's an invalid type in the config file
-	if !isValidString(globalSettings.GetString("type"), validTypes) {
-		changeConfig = true
-		globalSettings.Set("type", "site")
+	configFile, err := findConfigFile(path.Join(s.AppDirectory, "config"), "kana")
+	if err != nil {
+		return globalSettings, err
 	}
 
-	// Reset default php version if there's an invalid version in the config file
-	if !isValidString(globalSettings.GetString("php"), validPHPVersions) {
-		changeConfig = true
-		globalSettings.Set("php", "7.4")
+	globalSettings.SetConfigFile(configFile)
+
+	configInfo, err := os.Stat(configFile)
+	if os.IsNotExist(err) {
+		return globalSettings, writeConfig(globalSettings, configFile)
 	}
 
-	if changeConfig {
-		err = globalSettings.WriteConfig()
+	// Files written by older versions of Kana could be read by anyone, which exposed the admin password
+	if err == nil && configInfo.Mode().Perm() != configPermissions {
+		err = os.Chmod(configFile, configPermissions)
 		if err != nil {
 			return globalSettings, err
 		}
 	}
 
+	err = s.migrateConfigFile(configFile, globalScope)
+	if err != nil {
+		return globalSettings, err
+	}
+
+	err = globalSettings.ReadInConfig()
+	if err != nil {
+		return globalSettings, fmt.Errorf("%s is invalid: %s", configFile, err)
+	}
+
 	return globalSettings, nil
 }
@@ -1,9 +1,71 @@
 package settings
 
 import (
+	"fmt"
+	"net"
+	"os"
+	"path"
 	"strings"
+
+	"github.com/docker/distribution/reference"
 )
 
+// isValidImage Checks that an image from the catalogue is a valid Docker image reference
+func isValidImage(image string) bool {
+
+	_, err := reference.ParseNormalizedNamed(strings.ReplaceAll(image, "{php}", php))
+
+	return err == nil
+}
+
+// findConfigFile Returns the config file with the given name in a directory, in any of the supported formats. JSON is used if there isn't one yet.
+func findConfigFile(dir, name string) (string, error) {
+
+	found := []string{}
+
+	for _, extension := range configExtensions {
+
+		configFile := path.Join(dir, fmt.Sprintf("%s.%s", name, extension))
+
+		if _, err := os.Stat(configFile); err == nil {
+			found = append(found, configFile)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return path.Join(dir, fmt.Sprintf("%s.json", name)), nil
+	case 1:
+		return found[0], nil
+	}
+
+	return "", fmt.Errorf("found more than one config file: %s. Please keep only one of them", strings.Join(found, ", "))
+}
+
+// getLANIP Returns the first private IPv4 address assigned to one of the host's network interfaces
+func getLANIP() (net.IP, error) {
+
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, address := range addresses {
+
+		ipNet, ok := address.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() {
+			continue
+		}
+
+		ip := ipNet.IP.To4()
+		if ip != nil && ip.IsPrivate() {
+			return ip, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unable to find a LAN address for this machine. Please connect to a network or set the lan_hostname setting")
+}
+
 // isValidString Checks a given string against an array of valid values and returns true/false as appropriate
 func isValidString(stringToCheck string, validStrings []string) bool {
 
@@ -16,6 +78,44 @@ func isValidString(stringToCheck string, validStrings []string) bool {
 	return false
 }
 
+// levenshtein Returns the number of single character edits needed to turn one string into another
+func levenshtein(a, b string) int {
+
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = previous[j-1] + cost
+
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
 // sanitizeSiteName Returns the site name, properly sanitized for use.
 func sanitizeSiteName(rawSiteName string) string {
 
@@ -0,0 +1,312 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+
+	"github.com/aquasecurity/table"
+	"github.com/spf13/viper"
+)
+
+// layerRank The precedence of a layer of settings. Layers with a higher rank override those beneath them.
+type layerRank int
+
+const (
+	defaultLayer layerRank = iota
+	globalLayer
+	presetLayer
+	localLayer
+	environmentLayer
+	flagLayer
+	layerCount
+)
+
+// SettingValue A value for a setting along with the layer and the file, variable or flag it came from
+type SettingValue struct {
+	Value  interface{}
+	Layer  string
+	Source string
+}
+
+// SettingOrigin The effective value of a setting along with the values it overrode, or merged with for settings such as php_ini that combine their layers
+type SettingOrigin struct {
+	SettingValue
+	Key        string
+	Merged     bool
+	Overridden []SettingValue
+}
+
+// settingLayer The values one source, such as a config file, a preset or the start flags, sets by key
+type settingLayer map[string]SettingValue
+
+// getDefaultLayer Returns the layer holding the default value of every setting
+func getDefaultLayer() settingLayer {
+
+	layer := settingLayer{}
+
+	for key, value := range getDefaultSettings() {
+		layer[key] = SettingValue{Value: value, Layer: "default", Source: "default"}
+	}
+
+	return layer
+}
+
+// getFileLayer Returns the layer holding the settings in a config file that can be used in the given scope
+func getFileLayer(name string, config *viper.Viper, scope settingScope) settingLayer {
+
+	layer := settingLayer{}
+	keepCaseValues := getKeepCaseValues(config.ConfigFileUsed())
+
+	for _, schema := range settingsSchema {
+
+		if schema.scope&scope == 0 || !config.InConfig(schema.key) {
+			continue
+		}
+
+		value := getConfigValue(config, schema)
+		if keepCaseValue, ok := keepCaseValues[schema.key].(map[string]interface{}); ok {
+			value = keepCaseValue
+		}
+
+		layer[schema.key] = SettingValue{
+			Value:  value,
+			Layer:  name,
+			Source: config.ConfigFileUsed(),
+		}
+	}
+
+	return layer
+}
+
+// getConfigValue Reads a setting from a config file as the type it holds. Invalid values are reported when the config is validated.
+func getConfigValue(config *viper.Viper, schema setting) interface{} {
+
+	switch schema.valueType {
+	case boolSetting:
+		return config.GetBool(schema.key)
+	case listSetting, packageSetting:
+		// Plugins and themes keep any objects marking them active or inactive
+		if items, ok := config.Get(schema.key).([]interface{}); ok && schema.valueType == packageSetting {
+			return items
+		}
+
+		items := []interface{}{}
+
+		for _, item := range config.GetStringSlice(schema.key) {
+			items = append(items, item)
+		}
+
+		return items
+	case objectSetting:
+		return config.GetStringMap(schema.key)
+	}
+
+	return config.GetString(schema.key)
+}
+
+// getEnvironmentLayer Returns the layer holding the settings from valid KANA_* environment variables
+func (s *Settings) getEnvironmentLayer() settingLayer {
+
+	layer := settingLayer{}
+
+	for key, value := range s.environment {
+		layer[key] = SettingValue{Value: value, Layer: getEnvName(key), Source: getEnvName(key)}
+	}
+
+	return layer
+}
+
+// getFlagValue Returns a value set by one of the start command's flags
+func getFlagValue(flag string, value interface{}) SettingValue {
+
+	return SettingValue{Value: value, Layer: "flag", Source: fmt.Sprintf("--%s flag", flag)}
+}
+
+// resolveSetting Returns the value of a setting from the highest layer beneath the given rank that sets it, along with the values from lower layers
+func (s *Settings) resolveSetting(key string, below layerRank) SettingOrigin {
+
+	origin := SettingOrigin{
+		Key:        key,
+		Overridden: []SettingValue{},
+	}
+
+	// Directives in the php_ini setting are combined across layers rather than replacing the whole setting
+	origin.Merged = key == "php_ini"
+	merged := map[string]interface{}{}
+
+	for rank := defaultLayer; rank < below; rank++ {
+
+		value, ok := s.layers[rank][key]
+		if !ok {
+			continue
+		}
+
+		if origin.Source != "" {
+			origin.Overridden = append([]SettingValue{origin.SettingValue}, origin.Overridden...)
+		}
+
+		origin.SettingValue = value
+
+		if origin.Merged {
+			for name, directive := range getPHPIniDirectives("", value.Value.(map[string]interface{})) {
+				merged[name] = directive
+			}
+		}
+	}
+
+	if origin.Merged {
+		origin.Value = merged
+	}
+
+	return origin
+}
+
+// applySettings Sets every setting from the layers beneath the given rank. Values that can't be applied are kept so they can be reported when the config is validated.
+func (s *Settings) applySettings(below layerRank) {
+
+	s.PHPIni = map[string]interface{}{}
+	s.invalidSettings = []string{}
+
+	for _, schema := range settingsSchema {
+
+		origin := s.resolveSetting(schema.key, below)
+		if origin.Source == "" {
+			continue
+		}
+
+		err := s.setValue(schema.key, origin.Value)
+		if err != nil {
+			s.invalidSettings = append(s.invalidSettings, fmt.Sprintf("%s from %s couldn't be applied: %s", schema.key, origin.Source, err))
+		}
+	}
+}
+
+// getSettingSource Describes the layer the effective value of a setting came from for the config command
+func (s *Settings) getSettingSource(key string) string {
+
+	return s.resolveSetting(key, layerCount).Layer
+}
+
+// PrintSettingOrigins Prints the effective value of a setting, or of every setting when key is empty, along with the file, variable or flag it came from and the values it overrode
+func (s *Settings) PrintSettingOrigins(key string) error {
+
+	keys := []string{}
+
+	for _, schema := range settingsSchema {
+		if key == "" || schema.key == key {
+			keys = append(keys, schema.key)
+		}
+	}
+
+	if len(keys) == 0 {
+		return fmt.Errorf("invalid setting. Please enter a valid setting")
+	}
+
+	s.printOrigins(keys)
+
+	return nil
+}
+
+// PrintChangedSettingOrigins Prints the origins of the settings whose value differs from their default, which is what makes a site start differently from another
+func (s *Settings) PrintChangedSettingOrigins() {
+
+	keys := s.getChangedSettings()
+	if len(keys) == 0 {
+		console.Println("Every setting is using its default value.")
+		return
+	}
+
+	s.printOrigins(keys)
+}
+
+// getChangedSettings Returns the settings whose value differs from their default. The global config is written with every default so where a value came from isn't enough to tell.
+func (s *Settings) getChangedSettings() []string {
+
+	keys := []string{}
+
+	for _, schema := range settingsSchema {
+
+		origin := s.resolveSetting(schema.key, layerCount)
+		if origin.Source == "" {
+			continue
+		}
+
+		if defaultValue, ok := s.layers[defaultLayer][schema.key]; !ok || !reflect.DeepEqual(origin.Value, defaultValue.Value) {
+			keys = append(keys, schema.key)
+		}
+	}
+
+	return keys
+}
+
+// printOrigins Prints a table of the given settings along with the file, variable or flag each came from and the values it overrode
+func (s *Settings) printOrigins(keys []string) {
+
+	t := table.New(os.Stdout)
+
+	t.SetHeaders("Setting", "Value", "Source", "Overrides")
+
+	for _, settingKey := range keys {
+
+		origin := s.resolveSetting(settingKey, layerCount)
+		if origin.Source == "" {
+			continue
+		}
+
+		overridden := []string{}
+
+		for _, value := range origin.Overridden {
+			overridden = append(overridden, fmt.Sprintf("%s (%s)", formatSettingValue(settingKey, s.redactSetting(settingKey, value.Value)), value.Source))
+		}
+
+		// Merged settings keep the values beneath them
+		if origin.Merged && len(overridden) > 0 {
+			overridden[0] = fmt.Sprintf("merged with %s", overridden[0])
+		}
+
+		t.AddRow(settingKey, console.Bold(formatSettingValue(settingKey, s.redactSetting(settingKey, origin.Value))), origin.Source, strings.Join(overridden, "\n"))
+	}
+
+	t.Render()
+}
+
+// formatSettingValue Formats a setting's value for display the way it is entered with the config command
+func formatSettingValue(key string, value interface{}) string {
+
+	switch typedValue := value.(type) {
+	case string:
+		if typedValue == "" {
+			return "\"\""
+		}
+
+		return typedValue
+	case []interface{}:
+		if len(typedValue) == 0 {
+			return "[]"
+		}
+
+		items := []string{}
+
+		if key == "plugins" || key == "themes" {
+			items = describePackages(getPackages(typedValue, isActiveByDefault(key)), isActiveByDefault(key))
+		} else {
+			for _, item := range typedValue {
+				items = append(items, fmt.Sprint(item))
+			}
+		}
+
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		jsonValue, err := json.Marshal(typedValue)
+		if err == nil {
+			return string(jsonValue)
+		}
+	}
+
+	return fmt.Sprint(value)
+}
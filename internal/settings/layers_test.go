@@ -0,0 +1,122 @@
+package settings
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newLayeredSettings Returns settings with php set in every layer, php_ini set in several and xdebug only set by default
+func newLayeredSettings() Settings {
+
+	return Settings{
+		Images: map[string]string{},
+		layers: map[layerRank]settingLayer{
+			defaultLayer: {
+				"php":     {Value: "7.4", Layer: "default", Source: "default"},
+				"xdebug":  {Value: false, Layer: "default", Source: "default"},
+				"php_ini": {Value: map[string]interface{}{}, Layer: "default", Source: "default"},
+			},
+			globalLayer: {
+				"php":     {Value: "8.0", Layer: "global", Source: "kana.json"},
+				"php_ini": {Value: map[string]interface{}{"memory_limit": "512M", "upload_max_filesize": "64M"}, Layer: "global", Source: "kana.json"},
+			},
+			presetLayer: {"php": {Value: "8.1", Layer: "preset", Source: "woocommerce"}},
+			localLayer: {
+				"php":     {Value: "8.2", Layer: "local", Source: ".kana.json"},
+				"php_ini": {Value: map[string]interface{}{"memory_limit": "1G"}, Layer: "local", Source: ".kana.json"},
+			},
+			environmentLayer: {"php": {Value: "8.1", Layer: "environment", Source: "KANA_PHP"}},
+			flagLayer:        {"php": {Value: "8.2", Layer: "flag", Source: "--php"}},
+		},
+	}
+}
+
+func TestResolveSetting(t *testing.T) {
+
+	settings := newLayeredSettings()
+
+	tests := []struct {
+		key        string
+		below      layerRank
+		value      interface{}
+		source     string
+		overridden int
+	}{
+		{"php", layerCount, "8.2", "--php", 5},
+		{"php", flagLayer, "8.1", "KANA_PHP", 4},
+		{"php", environmentLayer, "8.2", ".kana.json", 3},
+		{"php", localLayer, "8.1", "woocommerce", 2},
+		{"php", presetLayer, "8.0", "kana.json", 1},
+		{"php", globalLayer, "7.4", "default", 0},
+		{"xdebug", layerCount, false, "default", 0},
+		{"lan", layerCount, nil, "", 0},
+	}
+
+	for _, test := range tests {
+
+		origin := settings.resolveSetting(test.key, test.below)
+
+		if origin.Value != test.value || origin.Source != test.source || len(origin.Overridden) != test.overridden {
+			t.Errorf("Expected %s below layer %d to be %v from %q overriding %d values; got %v from %q overriding %v", test.key, test.below, test.value, test.source, test.overridden, origin.Value, origin.Source, origin.Overridden)
+		}
+	}
+
+	// The closest value that was overridden comes first
+	origin := settings.resolveSetting("php", layerCount)
+	if origin.Overridden[0].Source != "KANA_PHP" || origin.Overridden[4].Source != "default" {
+		t.Errorf("Expected the overridden values to be listed from the highest layer down; got %v", origin.Overridden)
+	}
+}
+
+func TestResolvePHPIniMergesLayers(t *testing.T) {
+
+	settings := newLayeredSettings()
+
+	origin := settings.resolveSetting("php_ini", layerCount)
+
+	expected := map[string]interface{}{"memory_limit": "1G", "upload_max_filesize": "64M"}
+
+	if !origin.Merged || !reflect.DeepEqual(origin.Value, expected) {
+		t.Errorf("Expected php_ini to be merged to %v; got %v", expected, origin.Value)
+	}
+}
+
+func TestGetSettingSource(t *testing.T) {
+
+	settings := newLayeredSettings()
+
+	tests := map[string]string{
+		"php":     "flag",
+		"php_ini": "local",
+		"xdebug":  "default",
+		"lan":     "",
+	}
+
+	for key, expected := range tests {
+		if source := settings.getSettingSource(key); source != expected {
+			t.Errorf("Expected the source of %s to be %q; got %q", key, expected, source)
+		}
+	}
+}
+
+func TestGetChangedSettings(t *testing.T) {
+
+	settings := Settings{
+		Images: map[string]string{},
+		layers: map[layerRank]settingLayer{
+			defaultLayer: getDefaultLayer(),
+			globalLayer: {
+				"xdebug":  {Value: false, Layer: "global", Source: "kana.json"},
+				"php_ini": {Value: map[string]interface{}{}, Layer: "global", Source: "kana.json"},
+				"lan":     {Value: true, Layer: "global", Source: "kana.json"},
+			},
+			flagLayer: {"phpmyadmin": {Value: true, Layer: "flag", Source: "--phpmyadmin"}},
+		},
+	}
+
+	expected := []string{"lan", "phpmyadmin"}
+
+	if changed := settings.getChangedSettings(); !reflect.DeepEqual(changed, expected) {
+		t.Errorf("Expected only %v to be changed from their defaults; got %v", expected, changed)
+	}
+}
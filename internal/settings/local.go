@@ -5,6 +5,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -14,14 +15,16 @@ type StartFlags struct {
 	Xdebug     bool
 	PhpMyAdmin bool
 	Local      bool
+	Lan        bool
 	IsTheme    bool
 	IsPlugin   bool
+	Preset     string
 }
 
 type LocalSettings struct {
-	Local, PhpMyAdmin, Xdebug bool
-	Type                      string
-	Plugins                   []string
+	Lan, Local, PhpMyAdmin, SSL, Xdebug bool
+	PHP, Type                           string
+	Plugins, Themes                     []Package
 }
 
 // LoadLocalSettings Loads the config for the current site being called
@@ -43,29 +46,82 @@ func (s *Settings) LoadLocalSettings(cmd *cobra.Command) (bool, error) {
 		return isSite, err
 	}
 
+	// Presets sit between the global config and the site's config file
+	s.layers[presetLayer] = s.getPresetLayer()
+
+	// The site's config file falls back to the layers beneath it. This leaves out the environment so it is never written to the file.
+	s.applySettings(localLayer)
+
 	localViper, err := s.loadlocalViper()
 	if err != nil {
 		return isSite, err
 	}
 
 	s.local = localViper
-	s.Xdebug = localViper.GetBool("xdebug")
-	s.PhpMyAdmin = localViper.GetBool("phpmyadmin")
-	s.Local = localViper.GetBool("local")
-	s.PHP = localViper.GetString("php")
-	s.Type = localViper.GetString("type")
-	s.Plugins = localViper.GetStringSlice("plugins")
+	s.layers[localLayer] = getFileLayer("local", localViper, localScope)
+
+	// Environment variables override both config files but not the start flags, which are processed later
+	s.applySettings(layerCount)
 
 	return isSite, nil
 }
 
+// GetLANDomain Returns the domain sites are shared under on the local network, using nip.io when no hostname is configured
+func (s *Settings) GetLANDomain() (string, error) {
+
+	if s.LanHostname != "" {
+		return s.LanHostname, nil
+	}
+
+	ip, err := getLANIP()
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s.nip.io", ip.String()), nil
+}
+
+// GetImage Returns the image to use for the given catalogue entry, resolved for the site's PHP version
+func (s *Settings) GetImage(name string) string {
+
+	return strings.ReplaceAll(s.Images[name], "{php}", s.PHP)
+}
+
+// GetWordPressImage Returns the WordPress image for the site, using the tag for the site's WordPress version when it is a release and the official image is in use
+func (s *Settings) GetWordPressImage() string {
+
+	image := s.GetImage("wordpress")
+
+	if !IsWordPressRelease(s.WordPress) {
+		return image
+	}
+
+	repository := image
+	if tagIndex := strings.LastIndex(image, ":"); tagIndex > strings.LastIndex(image, "/") {
+		repository = image[:tagIndex]
+	}
+
+	// Custom images don't necessarily follow the official image's tags so they are left alone
+	if repository != "wordpress" && repository != "library/wordpress" && repository != "docker.io/library/wordpress" {
+		return image
+	}
+
+	return fmt.Sprintf("%s:%s-php%s", repository, s.WordPress, s.PHP)
+}
+
+// IsWordPressRelease Checks if a WordPress version is a final release, which are the only versions available as images
+func IsWordPressRelease(version string) bool {
+
+	return wordPressVersionPattern.MatchString(version) && !strings.Contains(version, "-")
+}
+
 // ProcessNameFlag Processes the name flag on the site resetting all appropriate local variables
 func (s *Settings) ProcessNameFlag(cmd *cobra.Command) (bool, error) {
 
 	isSite := false // Don't assume we're in a site that has been initialized.
 
-	// Don't run this on commands that wouldn't possibly use it.
-	if cmd.Use == "config" || cmd.Use == "version" || cmd.Use == "help" {
+	// Don't run this on commands that wouldn't possibly use it. The config command needs the site's link.json to show where its settings came from.
+	if cmd.Use == "version" || cmd.Use == "help" {
 		return isSite, nil
 	}
 
@@ -99,12 +155,18 @@ func (s *Settings) ProcessNameFlag(cmd *cobra.Command) (bool, error) {
 
 	siteLinkConfig := viper.New()
 
+	siteLinkConfig.SetDefault(versionKey, configVersion)
 	siteLinkConfig.SetDefault("link", siteLink)
 
 	siteLinkConfig.SetConfigName("link")
 	siteLinkConfig.SetConfigType("json")
 	siteLinkConfig.AddConfigPath(s.SiteDirectory)
 
+	err = s.migrateConfigFile(path.Join(s.SiteDirectory, "link.json"), linkScope)
+	if err != nil {
+		return isSite, err
+	}
+
 	err = siteLinkConfig.ReadInConfig()
 	if err != nil {
 		_, ok := err.(viper.ConfigFileNotFoundError)
@@ -114,7 +176,7 @@ func (s *Settings) ProcessNameFlag(cmd *cobra.Command) (bool, error) {
 			if err != nil {
 				return isSite, err
 			}
-			err = siteLinkConfig.SafeWriteConfig()
+			err = writeConfig(siteLinkConfig, path.Join(s.SiteDirectory, "link.json"))
 			if err != nil {
 				return isSite, err
 			}
@@ -122,6 +184,27 @@ func (s *Settings) ProcessNameFlag(cmd *cobra.Command) (bool, error) {
 	}
 
 	s.WorkingDirectory = siteLinkConfig.GetString("link")
+	s.Preset = siteLinkConfig.GetString("preset")
+
+	// The preset used to start a site is saved so the site keeps the same settings when it is started again
+	if cmd.Use == "start" && cmd.Flags().Lookup("preset").Changed {
+
+		s.Preset = cmd.Flags().Lookup("preset").Value.String()
+
+		if s.Preset != "" {
+			_, err = s.GetPreset(s.Preset)
+			if err != nil {
+				return isSite, err
+			}
+		}
+
+		siteLinkConfig.Set("preset", s.Preset)
+
+		err = writeConfig(siteLinkConfig, path.Join(s.SiteDirectory, "link.json"))
+		if err != nil {
+			return isSite, err
+		}
+	}
 
 	return isSite, nil
 }
@@ -129,66 +212,110 @@ func (s *Settings) ProcessNameFlag(cmd *cobra.Command) (bool, error) {
 // ProcessStartFlags Process the start flags and save them to the settings object
 func (s *Settings) ProcessStartFlags(cmd *cobra.Command, flags StartFlags) {
 
+	layer := settingLayer{}
+
 	if cmd.Flags().Lookup("local").Changed {
-		s.Local = flags.Local
+		layer["local"] = getFlagValue("local", flags.Local)
 	}
 
 	if cmd.Flags().Lookup("xdebug").Changed {
-		s.Xdebug = flags.Xdebug
+		layer["xdebug"] = getFlagValue("xdebug", flags.Xdebug)
+	}
+
+	if cmd.Flags().Lookup("lan").Changed {
+		layer["lan"] = getFlagValue("lan", flags.Lan)
 	}
 
 	if cmd.Flags().Lookup("phpmyadmin").Changed {
-		s.PhpMyAdmin = flags.PhpMyAdmin
+		layer["phpmyadmin"] = getFlagValue("phpmyadmin", flags.PhpMyAdmin)
 	}
 
 	if cmd.Flags().Lookup("plugin").Changed && flags.IsPlugin {
-		s.Type = "plugih"
+		layer["type"] = getFlagValue("plugin", "plugin")
 	}
 
 	if cmd.Flags().Lookup("theme").Changed && flags.IsTheme {
-		s.Type = "theme"
+		layer["type"] = getFlagValue("theme", "theme")
 	}
+
+	s.layers[flagLayer] = layer
+
+	s.applySettings(layerCount)
 }
 
 // WriteLocalSettings Writes all appropriate local settings to the local config file
 func (s *Settings) WriteLocalSettings(localSettings LocalSettings) error {
 
-	s.local.Set("local", localSettings.Local)
-	s.local.Set("type", localSettings.Type)
-	s.local.Set("xdebug", localSettings.Xdebug)
-	s.local.Set("phpmyadmin", localSettings.PhpMyAdmin)
-	s.local.Set("plugins", localSettings.Plugins)
+	values := map[string]interface{}{
+		"local":      localSettings.Local,
+		"lan":        localSettings.Lan,
+		"ssl":        localSettings.SSL,
+		"php":        localSettings.PHP,
+		"type":       localSettings.Type,
+		"xdebug":     localSettings.Xdebug,
+		"phpmyadmin": localSettings.PhpMyAdmin,
+		"plugins":    getPackagesConfig(localSettings.Plugins, true),
+		"themes":     getPackagesConfig(localSettings.Themes, false),
+		versionKey:   configVersion,
+	}
 
-	if _, err := os.Stat(path.Join(s.WorkingDirectory, ".kana.json")); os.IsNotExist(err) {
-		return s.local.SafeWriteConfig()
+	for key, value := range values {
+		s.local.Set(key, value)
 	}
 
-	return s.local.WriteConfig()
+	// The config file was found when the site was loaded so this keeps whichever format is in use
+	return editConfigFile(s.local.ConfigFileUsed(), values, []string{})
 }
 
-// loadSiteConfig Get the config items that can be overridden locally with a .kana.json file.
+// loadSiteConfig Get the config items that can be overridden locally with a .kana.json, .kana.yaml, .kana.yml or .kana.toml file.
 func (s *Settings) loadlocalViper() (*viper.Viper, error) {
 
 	localSettings := viper.New()
 
 	localSettings.SetDefault("php", s.PHP)
+	localSettings.SetDefault("wordpress", s.WordPress)
 	localSettings.SetDefault("type", s.Type)
 	localSettings.SetDefault("local", s.Local)
+	localSettings.SetDefault("lan", s.Lan)
+	localSettings.SetDefault("ssl", ssl)
 	localSettings.SetDefault("xdebug", s.Xdebug)
 	localSettings.SetDefault("phpmyadmin", s.PhpMyAdmin)
-	localSettings.SetDefault("plugins", []string{})
+	localSettings.SetDefault("plugins", getPackagesConfig(s.Plugins, true))
+	localSettings.SetDefault("themes", getPackagesConfig(s.Themes, false))
+	localSettings.SetDefault("options", s.Options)
 
-	localSettings.SetConfigName(".kana")
-	localSettings.SetConfigType("json")
-	localSettings.AddConfigPath(s.WorkingDirectory)
+	for name, image := range s.Images {
+		localSettings.SetDefault(fmt.Sprintf("images.%s", name), image)
+	}
 
-	err := localSettings.ReadInConfig()
+	configFile, err := findConfigFile(s.WorkingDirectory, ".kana")
 	if err != nil {
-		_, ok := err.(viper.ConfigFileNotFoundError)
-		if !ok {
+		return localSettings, err
+	}
+
+	localSettings.SetConfigFile(configFile)
+
+	if _, err = os.Stat(configFile); os.IsNotExist(err) {
+		return localSettings, nil
+	}
+
+	err = s.migrateConfigFile(configFile, localScope)
+	if err != nil {
+		return localSettings, err
+	}
+
+	// Like migrations, the config and preset commands leave the file as it is
+	if !s.SkipMigrations {
+		err = hashConfigFilePasswords(configFile)
+		if err != nil {
 			return localSettings, err
 		}
 	}
 
+	err = localSettings.ReadInConfig()
+	if err != nil {
+		return localSettings, fmt.Errorf("%s is invalid: %s", configFile, err)
+	}
+
 	return localSettings, nil
 }
@@ -0,0 +1,162 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/net/http/httpguts"
+)
+
+// Middlewares Traefik middlewares applied to a site's WordPress router
+type Middlewares struct {
+	BasicAuth   []string          `mapstructure:"basic_auth"`
+	Headers     map[string]string `mapstructure:"headers"`
+	StripPrefix []string          `mapstructure:"strip_prefix"`
+	WWWRedirect bool              `mapstructure:"www_redirect"`
+}
+
+// The hash prefixes Traefik accepts in basic auth users
+var htpasswdPrefixes = []string{
+	"$apr1$",
+	"$2a$",
+	"$2b$",
+	"$2y$",
+	"{SHA}",
+}
+
+// GetBasicAuthUsers Returns the basic auth users in htpasswd format, hashing any plain text passwords.
+// Passwords in config files are hashed when the file is loaded so only those set by environment variables or flags are hashed here.
+func (m Middlewares) GetBasicAuthUsers() ([]string, error) {
+
+	users := []string{}
+
+	for _, user := range m.BasicAuth {
+
+		hashedUser, err := hashBasicAuthUser(user)
+		if err != nil {
+			return users, err
+		}
+
+		users = append(users, hashedUser)
+	}
+
+	return users, nil
+}
+
+// hashBasicAuthUser Replaces the password of a basic auth user in the form "username:password" with a bcrypt hash unless it is already hashed
+func hashBasicAuthUser(user string) (string, error) {
+
+	name, password, found := strings.Cut(user, ":")
+
+	// Users without a password are reported when the config is validated
+	if !found || password == "" || isHashedPassword(password) {
+		return user, nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return user, err
+	}
+
+	return fmt.Sprintf("%s:%s", name, hash), nil
+}
+
+// hashBasicAuthPasswords Hashes the plain text passwords of the basic_auth users in a middlewares value, returning true if any were changed
+func hashBasicAuthPasswords(middlewares map[string]interface{}) (bool, error) {
+
+	users, ok := middlewares["basic_auth"].([]interface{})
+	if !ok {
+		return false, nil
+	}
+
+	changed := false
+	hashedUsers := []interface{}{}
+
+	for _, user := range users {
+
+		// Anything that isn't a string is reported when the config is validated
+		userString, ok := user.(string)
+		if !ok {
+			hashedUsers = append(hashedUsers, user)
+			continue
+		}
+
+		hashedUser, err := hashBasicAuthUser(userString)
+		if err != nil {
+			return false, err
+		}
+
+		changed = changed || hashedUser != userString
+		hashedUsers = append(hashedUsers, hashedUser)
+	}
+
+	middlewares["basic_auth"] = hashedUsers
+
+	return changed, nil
+}
+
+// hashConfigFilePasswords Replaces the plain text basic auth passwords in a site's config file with hashes so they aren't committed along with it.
+// Hashing them once also keeps the site's Traefik labels the same each time it starts.
+func hashConfigFilePasswords(configFile string) error {
+
+	middlewares, ok := getKeepCaseValues(configFile)["middlewares"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	changed, err := hashBasicAuthPasswords(middlewares)
+	if err != nil || !changed {
+		return err
+	}
+
+	err = editConfigFile(configFile, map[string]interface{}{"middlewares.basic_auth": middlewares["basic_auth"]}, []string{})
+	if err != nil {
+		return err
+	}
+
+	console.Println(fmt.Sprintf("The basic auth passwords in %s have been replaced with hashes.", configFile))
+
+	return nil
+}
+
+// validate Checks the middlewares for anything Traefik would reject
+func (m Middlewares) validate() error {
+
+	for i, user := range m.BasicAuth {
+
+		name, password, found := strings.Cut(user, ":")
+
+		if !found || name == "" || password == "" {
+			return fmt.Errorf("invalid middlewares.basic_auth[%d]: users must be in the form \"username:password\"", i)
+		}
+	}
+
+	for header := range m.Headers {
+		if !httpguts.ValidHeaderFieldName(header) {
+			return fmt.Errorf("invalid middlewares.headers key %q: please use a valid HTTP header name", header)
+		}
+	}
+
+	for i, prefix := range m.StripPrefix {
+		if !strings.HasPrefix(prefix, "/") || strings.ContainsAny(prefix, " ,") {
+			return fmt.Errorf("invalid middlewares.strip_prefix[%d] %q: prefixes must start with \"/\" and can't contain spaces or commas", i, prefix)
+		}
+	}
+
+	return nil
+}
+
+// isHashedPassword Checks if a basic auth password has already been hashed for htpasswd
+func isHashedPassword(password string) bool {
+
+	for _, prefix := range htpasswdPrefixes {
+		if strings.HasPrefix(password, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
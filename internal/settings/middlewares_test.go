@@ -0,0 +1,99 @@
+package settings
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMiddlewareHeadersKeepCase(t *testing.T) {
+
+	configFile := writeTestFile(t, ".kana.json", `{"middlewares": {"headers": {"X-Frame-Options": "SAMEORIGIN"}}}`)
+
+	config := viper.New()
+	config.SetConfigFile(configFile)
+
+	err := config.ReadInConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings := Settings{
+		Images: map[string]string{},
+		layers: map[layerRank]settingLayer{
+			localLayer: getFileLayer("local", config, localScope),
+		},
+	}
+
+	settings.applySettings(layerCount)
+
+	if value := settings.Middlewares.Headers["X-Frame-Options"]; value != "SAMEORIGIN" {
+		t.Errorf("Expected the X-Frame-Options header to keep its case; got %v", settings.Middlewares.Headers)
+	}
+
+	err = validateConfigFile(configFile, localScope)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInvalidMiddlewaresAreReported(t *testing.T) {
+
+	settings := Settings{
+		Images: map[string]string{},
+		layers: map[layerRank]settingLayer{
+			flagLayer: {"middlewares": {Value: map[string]interface{}{"headers": "X-Frame-Options"}, Layer: "flag", Source: "test"}},
+		},
+	}
+
+	settings.applySettings(layerCount)
+
+	if len(settings.invalidSettings) != 1 {
+		t.Errorf("Expected the invalid middlewares to be reported; got %v", settings.invalidSettings)
+	}
+}
+
+func TestHashConfigFilePasswords(t *testing.T) {
+
+	configFile := writeTestFile(t, ".kana.json", `{"middlewares": {"basic_auth": ["staging:supersecret", "admin:$apr1$K3fo1bXm$BdZqkvmThm7RiWZ4AmsOv0"], "headers": {"X-Frame-Options": "SAMEORIGIN"}}}`)
+
+	err := hashConfigFilePasswords(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	middlewares := getKeepCaseValues(configFile)["middlewares"].(map[string]interface{})
+	users := middlewares["basic_auth"].([]interface{})
+
+	name, hash, _ := strings.Cut(users[0].(string), ":")
+	if name != "staging" || bcrypt.CompareHashAndPassword([]byte(hash), []byte("supersecret")) != nil {
+		t.Errorf("Expected the plain text password to be replaced with its hash; got %s", users[0])
+	}
+
+	if users[1] != "admin:$apr1$K3fo1bXm$BdZqkvmThm7RiWZ4AmsOv0" {
+		t.Errorf("Expected the hashed password to be left alone; got %s", users[1])
+	}
+
+	if _, ok := middlewares["headers"].(map[string]interface{})["X-Frame-Options"]; !ok {
+		t.Errorf("Expected the headers to keep their case; got %v", middlewares["headers"])
+	}
+
+	// Once hashed the file, and so the site's labels, stay the same
+	contents := readTestFile(t, configFile)
+
+	err = hashConfigFilePasswords(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if readTestFile(t, configFile) != contents {
+		t.Error("Expected a file without plain text passwords to be left alone")
+	}
+
+	labelUsers, err := Middlewares{BasicAuth: []string{users[0].(string)}}.GetBasicAuthUsers()
+	if err != nil || labelUsers[0] != users[0] {
+		t.Errorf("Expected the hashed user to be used as it is; got %v", labelUsers)
+	}
+}
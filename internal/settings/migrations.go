@@ -0,0 +1,230 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+
+	"github.com/spf13/viper"
+)
+
+// configVersion The version of the config files written by this version of Kana. Increase it when adding a migration.
+const configVersion = 1
+
+// The key holding the version of a config file
+const versionKey = "version"
+
+// migration Upgrades the settings read from an older config file to a newer version, returning a description of each change it made
+type migration struct {
+	version int
+	scope   settingScope
+	migrate func(settings map[string]interface{}) []string
+}
+
+// The migrations for each config file, in order
+var migrations = []migration{
+	{version: 1, scope: globalScope, migrate: resetUnsupportedValues},
+	{version: 1, scope: localScope, migrate: splitPluginsString},
+}
+
+// pendingMigration A config file that needs to be upgraded but was left alone because the command only reads the config
+type pendingMigration struct {
+	configFile string
+	scope      settingScope
+}
+
+// MigrateConfig Upgrades any config files that were left alone when the config was loaded, returning the files that were changed
+func (s *Settings) MigrateConfig() ([]string, error) {
+
+	migrated := []string{}
+	s.SkipMigrations = false
+
+	for _, pending := range s.pendingMigrations {
+
+		err := s.migrateConfigFile(pending.configFile, pending.scope)
+		if err != nil {
+			return migrated, err
+		}
+
+		migrated = append(migrated, pending.configFile)
+	}
+
+	s.pendingMigrations = []pendingMigration{}
+
+	return migrated, nil
+}
+
+// getMigrationProblems Describes the config files that need to be upgraded before they can be used by this version of Kana
+func (s *Settings) getMigrationProblems() []string {
+
+	problems := []string{}
+
+	for _, pending := range s.pendingMigrations {
+		problems = append(problems, fmt.Sprintf("%s needs to be migrated to this version of Kana. Run `kana config migrate` or `kana start` to upgrade it", pending.configFile))
+	}
+
+	return problems
+}
+
+// migrateConfigFile Upgrades a config file written by an older version of Kana to the current version, keeping a backup of the original file and warning about any changes. Files are only recorded as needing an upgrade when migrations are skipped.
+func (s *Settings) migrateConfigFile(configFile string, scope settingScope) error {
+
+	original, err := os.ReadFile(configFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	fileConfig := viper.New()
+	fileConfig.SetConfigFile(configFile)
+
+	err = fileConfig.ReadInConfig()
+	if err != nil {
+		return fmt.Errorf("%s is invalid: %s", configFile, err)
+	}
+
+	version := fileConfig.GetInt(versionKey)
+
+	if version > configVersion {
+		return fmt.Errorf("%s was written by a newer version of Kana (config version %d). Please update Kana to use it", configFile, version)
+	}
+
+	if version == configVersion {
+		return nil
+	}
+
+	settings := fileConfig.AllSettings()
+	changes := []string{}
+
+	for _, migration := range migrations {
+		if migration.version > version && migration.scope&scope != 0 {
+			changes = append(changes, migration.migrate(settings)...)
+		}
+	}
+
+	// Files that are already in the current format are left as they are rather than rewritten just to add the version
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if s.SkipMigrations {
+		s.pendingMigrations = append(s.pendingMigrations, pendingMigration{configFile, scope})
+		return nil
+	}
+
+	settings[versionKey] = configVersion
+	changes = append(changes, fmt.Sprintf("set the config version to %d", configVersion))
+
+	backupFile, err := s.backupConfigFile(configFile, scope, version, original)
+	if err != nil {
+		return err
+	}
+
+	// Only the settings the migrations changed are written so the rest of the file, including any comments, is kept
+	values := map[string]interface{}{}
+	unset := []string{}
+	originalSettings := fileConfig.AllSettings()
+
+	for key, value := range settings {
+		if !reflect.DeepEqual(originalSettings[key], value) {
+			values[key] = value
+		}
+	}
+
+	for key := range originalSettings {
+		if _, ok := settings[key]; !ok {
+			unset = append(unset, key)
+		}
+	}
+
+	err = editConfigFile(configFile, values, unset)
+	if err != nil {
+		return fmt.Errorf("%s. It needs the following changes to work with this version of Kana:\n  - %s", err, strings.Join(changes, "\n  - "))
+	}
+
+	console.Warn(fmt.Sprintf("%s was upgraded for this version of Kana and the original was saved to %s. The following changes were made:\n  - %s", configFile, backupFile, strings.Join(changes, "\n  - ")))
+
+	return nil
+}
+
+// backupConfigFile Saves the original contents of a config file in the app directory, rather than next to a site's files, before it is upgraded
+func (s *Settings) backupConfigFile(configFile string, scope settingScope, version int, original []byte) (string, error) {
+
+	backupPath := path.Join(s.AppDirectory, "backups", fmt.Sprintf("config-%s", time.Now().Format("20060102-150405")))
+
+	err := os.MkdirAll(backupPath, 0750)
+	if err != nil {
+		return "", err
+	}
+
+	// Every site has its own .kana.json and link.json so they are named after the site
+	backupName := filepath.Base(configFile)
+	if scope != globalScope {
+		backupName = fmt.Sprintf("%s.%s", s.Name, strings.TrimPrefix(backupName, "."))
+	}
+
+	backupFile := path.Join(backupPath, fmt.Sprintf("%s.v%d.bak", backupName, version))
+
+	return backupFile, os.WriteFile(backupFile, original, configPermissions)
+}
+
+// resetUnsupportedValues Resets php and type values that older versions of Kana silently replaced with their defaults
+func resetUnsupportedValues(settings map[string]interface{}) []string {
+
+	changes := []string{}
+
+	for _, reset := range []struct {
+		key          string
+		validValues  []string
+		defaultValue string
+	}{
+		{"php", validPHPVersions, php},
+		{"type", validTypes, siteType},
+	} {
+
+		value, ok := settings[reset.key]
+		if !ok {
+			continue
+		}
+
+		stringValue, isString := value.(string)
+		if isString && isValidString(stringValue, reset.validValues) {
+			continue
+		}
+
+		settings[reset.key] = reset.defaultValue
+		changes = append(changes, fmt.Sprintf("changed %s from %v, which isn't supported, to %s", reset.key, value, reset.defaultValue))
+	}
+
+	return changes
+}
+
+// splitPluginsString Converts a plugins setting saved as a comma separated string to a list
+func splitPluginsString(settings map[string]interface{}) []string {
+
+	plugins, ok := settings["plugins"].(string)
+	if !ok {
+		return []string{}
+	}
+
+	pluginList := []interface{}{}
+
+	for _, plugin := range strings.Split(plugins, ",") {
+		if plugin = strings.TrimSpace(plugin); plugin != "" {
+			pluginList = append(pluginList, plugin)
+		}
+	}
+
+	settings["plugins"] = pluginList
+
+	return []string{fmt.Sprintf("changed plugins from the string \"%s\" to a list", plugins)}
+}
@@ -0,0 +1,270 @@
+package settings
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// The places a plugin or theme can be installed from
+const (
+	PackageWordPressOrg = "wordpress.org"
+	PackageURL          = "url"
+	PackageZip          = "zip"
+	PackageDirectory    = "directory"
+)
+
+// WordPress.org slugs with an optional version such as woocommerce@8.2.1
+var packageSlugPattern = regexp.MustCompile(`^[a-z0-9_-]+(@[0-9A-Za-z.+-]+)?$`)
+
+// Package A plugin or theme to install on a site. The source is a WordPress.org slug with an optional version, the URL of a zip file, or the path to a local zip file or directory.
+type Package struct {
+	Source string
+	Active bool
+}
+
+// Type Returns where the package is installed from
+func (p Package) Type() string {
+
+	if strings.HasPrefix(p.Source, "http://") || strings.HasPrefix(p.Source, "https://") {
+		return PackageURL
+	}
+
+	if isPackagePath(p.Source) {
+
+		if strings.HasSuffix(strings.ToLower(p.Source), ".zip") {
+			return PackageZip
+		}
+
+		return PackageDirectory
+	}
+
+	return PackageWordPressOrg
+}
+
+// Name Returns the name WordPress will know the package by. For zip files this is a best guess from the file name.
+func (p Package) Name() string {
+
+	switch p.Type() {
+	case PackageWordPressOrg:
+		name, _, _ := strings.Cut(p.Source, "@")
+		return name
+	case PackageURL:
+		sourceURL, err := url.Parse(p.Source)
+		if err == nil {
+			return strings.TrimSuffix(path.Base(sourceURL.Path), ".zip")
+		}
+	}
+
+	return strings.TrimSuffix(filepath.Base(p.Source), ".zip")
+}
+
+// Version Returns the version requested for a WordPress.org package, if there is one
+func (p Package) Version() string {
+
+	if p.Type() != PackageWordPressOrg {
+		return ""
+	}
+
+	_, version, _ := strings.Cut(p.Source, "@")
+
+	return version
+}
+
+// GetPath Returns the full path to a local zip file or directory, resolving relative paths from the site's working directory
+func (p Package) GetPath(workingDirectory string) (string, error) {
+
+	packagePath, err := homedir.Expand(p.Source)
+	if err != nil {
+		return "", err
+	}
+
+	if !filepath.IsAbs(packagePath) {
+		packagePath = filepath.Join(workingDirectory, packagePath)
+	}
+
+	return packagePath, nil
+}
+
+// configValue Returns the package as it is written in a config file. Packages that use the default activation are written as just their source.
+func (p Package) configValue(activeByDefault bool) interface{} {
+
+	if p.Active == activeByDefault {
+		return p.Source
+	}
+
+	return map[string]interface{}{
+		"source": p.Source,
+		"active": p.Active,
+	}
+}
+
+// isActiveByDefault Plugins are activated when they are installed unless the config says otherwise while themes need to be marked active
+func isActiveByDefault(key string) bool {
+
+	return key == "plugins"
+}
+
+// describePackages Lists packages for the config command, noting any that don't use the default activation
+func describePackages(packages []Package, activeByDefault bool) []string {
+
+	descriptions := []string{}
+
+	for _, configPackage := range packages {
+
+		description := configPackage.Source
+
+		if configPackage.Active != activeByDefault {
+			if configPackage.Active {
+				description = fmt.Sprintf("%s (active)", description)
+			} else {
+				description = fmt.Sprintf("%s (inactive)", description)
+			}
+		}
+
+		descriptions = append(descriptions, description)
+	}
+
+	return descriptions
+}
+
+// getPackages Converts a plugins or themes setting to packages. Entries can be a source or an object with a source and whether it should be active.
+func getPackages(value interface{}, activeByDefault bool) []Package {
+
+	packages := []Package{}
+
+	items, ok := value.([]interface{})
+	if !ok {
+
+		// Values set from code, such as defaults, may already be packages
+		if existing, ok := value.([]Package); ok {
+			return existing
+		}
+
+		return packages
+	}
+
+	for _, item := range items {
+
+		switch typedItem := item.(type) {
+		case string:
+			packages = append(packages, Package{Source: typedItem, Active: activeByDefault})
+		case map[string]interface{}:
+			source, _ := typedItem["source"].(string)
+			active, ok := typedItem["active"].(bool)
+			if !ok {
+				active = activeByDefault
+			}
+
+			packages = append(packages, Package{Source: source, Active: active})
+		}
+	}
+
+	return packages
+}
+
+// getPackagesConfig Returns packages as they are written in a config file
+func getPackagesConfig(packages []Package, activeByDefault bool) []interface{} {
+
+	values := []interface{}{}
+
+	for _, configPackage := range packages {
+		values = append(values, configPackage.configValue(activeByDefault))
+	}
+
+	return values
+}
+
+// isPackagePath Checks if a package source is a local path rather than a slug or URL
+func isPackagePath(source string) bool {
+
+	return filepath.IsAbs(source) || strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") || strings.HasPrefix(source, "~/")
+}
+
+// checkPackageList Makes sure every entry in a plugins or themes setting is a source or an object with a source and an optional active flag
+func checkPackageList(items []interface{}) error {
+
+	for _, item := range items {
+
+		switch typedItem := item.(type) {
+		case string:
+		case map[string]interface{}:
+			if _, ok := typedItem["source"].(string); !ok {
+				return fmt.Errorf("every object needs a \"source\" string")
+			}
+
+			for key, value := range typedItem {
+
+				switch key {
+				case "source":
+				case "active":
+					if _, ok := value.(bool); !ok {
+						return fmt.Errorf("\"active\" must be true or false but got %s", describeValue(value))
+					}
+				default:
+					return fmt.Errorf("unknown key \"%s\". Entries can only have a \"source\" and \"active\"", key)
+				}
+			}
+		default:
+			return fmt.Errorf("expected a source or an object but it contains %s", describeValue(item))
+		}
+	}
+
+	return nil
+}
+
+// validatePackages Checks that every source in a plugins or themes setting can be installed
+func validatePackages(value interface{}) error {
+
+	problems := []string{}
+
+	for _, configPackage := range getPackages(value, false) {
+
+		switch configPackage.Type() {
+		case PackageWordPressOrg:
+			if !packageSlugPattern.MatchString(configPackage.Source) {
+				problems = append(problems, fmt.Sprintf("\"%s\" is not a valid WordPress.org slug", configPackage.Source))
+			}
+		case PackageURL:
+			if _, err := url.ParseRequestURI(configPackage.Source); err != nil {
+				problems = append(problems, fmt.Sprintf("\"%s\" is not a valid URL", configPackage.Source))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// validateThemes Checks the themes setting like any other packages and makes sure only one of them is active
+func validateThemes(value interface{}) error {
+
+	err := validatePackages(value)
+	if err != nil {
+		return err
+	}
+
+	active := []string{}
+
+	for _, theme := range getPackages(value, false) {
+		if theme.Active {
+			active = append(active, theme.Source)
+		}
+	}
+
+	if len(active) > 1 {
+		return fmt.Errorf("only one theme can be active but %s are all marked active", strings.Join(active, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package settings
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// php.ini directives are made of letters, numbers, underscores and dots such as "opcache.enable"
+var phpIniDirectivePattern = regexp.MustCompile(`^[a-zA-Z0-9_.]+$`)
+
+// Values made of these characters don't need to be quoted in php.ini
+var phpIniPlainValuePattern = regexp.MustCompile(`^[a-zA-Z0-9_./:+-]*$`)
+
+// GetPHPIni Returns the contents of the php.ini file Kana adds to the site's PHP configuration from the php_ini setting
+func (s *Settings) GetPHPIni() string {
+
+	directives := getPHPIniDirectives("", s.PHPIni)
+	names := []string{}
+
+	for name := range directives {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	lines := []string{
+		"; Generated by Kana from the php_ini setting. Changes made here are replaced when the site starts.",
+	}
+
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%s = %s", name, formatPHPIniValue(directives[name])))
+	}
+
+	return fmt.Sprintf("%s\n", strings.Join(lines, "\n"))
+}
+
+// mergePHPIni Adds the directives from the site's config to those from the global config, replacing any that are set in both
+func (s *Settings) mergePHPIni(values map[string]interface{}) {
+
+	merged := map[string]interface{}{}
+
+	for name, value := range getPHPIniDirectives("", s.PHPIni) {
+		merged[name] = value
+	}
+
+	for name, value := range getPHPIniDirectives("", values) {
+		merged[name] = value
+	}
+
+	s.PHPIni = merged
+}
+
+// getPHPIniDirectives Flattens the php_ini setting into directives. Config files treat dots as nesting so "opcache.enable" can also be written as an "enable" key inside "opcache".
+func getPHPIniDirectives(prefix string, values map[string]interface{}) map[string]interface{} {
+
+	directives := map[string]interface{}{}
+
+	for name, value := range values {
+
+		if prefix != "" {
+			name = fmt.Sprintf("%s.%s", prefix, name)
+		}
+
+		if nestedValues, ok := value.(map[string]interface{}); ok {
+
+			for nestedName, nestedValue := range getPHPIniDirectives(name, nestedValues) {
+				directives[nestedName] = nestedValue
+			}
+
+			continue
+		}
+
+		directives[name] = value
+	}
+
+	return directives
+}
+
+// formatPHPIniValue Formats a value from the php_ini setting for php.ini, quoting strings that contain anything other than simple characters
+func formatPHPIniValue(value interface{}) string {
+
+	switch typedValue := value.(type) {
+	case bool:
+		if typedValue {
+			return "On"
+		}
+
+		return "Off"
+	case float64:
+		return strconv.FormatFloat(typedValue, 'f', -1, 64)
+	case string:
+		if phpIniPlainValuePattern.MatchString(typedValue) {
+			return typedValue
+		}
+
+		return fmt.Sprintf("\"%s\"", typedValue)
+	}
+
+	return fmt.Sprint(value)
+}
+
+// validatePHPIni Checks that every directive in the php_ini setting has a valid name and a value that can be written to php.ini
+func validatePHPIni(value interface{}) error {
+
+	problems := []string{}
+
+	for name, directiveValue := range getPHPIniDirectives("", value.(map[string]interface{})) {
+
+		if !phpIniDirectivePattern.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("\"%s\" is not a valid php.ini directive", name))
+			continue
+		}
+
+		switch typedValue := directiveValue.(type) {
+		case bool, int, int64, float64:
+		case string:
+			if strings.ContainsAny(typedValue, "\"\n") {
+				problems = append(problems, fmt.Sprintf("%s can't contain quotes or new lines", name))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("%s must be a string, number or boolean but got %s", name, describeValue(directiveValue)))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,335 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+
+	"github.com/aquasecurity/table"
+	"github.com/spf13/viper"
+)
+
+// Preset A named set of site settings that can be applied when a site is started
+type Preset struct {
+	Name     string
+	Source   string
+	Settings map[string]interface{}
+}
+
+// ListPresets Lists the presets in the global config and the presets folder for the preset command
+func (s *Settings) ListPresets() error {
+
+	presets, err := s.getPresets()
+	if err != nil {
+		return err
+	}
+
+	if len(presets) == 0 {
+		console.Println("There are no presets yet. Use \"kana preset save\" to save a running site as a preset.")
+		return nil
+	}
+
+	t := table.New(os.Stdout)
+
+	t.SetHeaders("Name", "Settings", "Source")
+
+	for _, preset := range presets {
+
+		keys := []string{}
+
+		for key := range preset.Settings {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		t.AddRow(console.Bold(preset.Name), strings.Join(keys, ", "), preset.Source)
+	}
+
+	t.Render()
+
+	return nil
+}
+
+// PrintPreset Prints the settings in a preset for the preset command
+func (s *Settings) PrintPreset(name string) error {
+
+	preset, err := s.GetPreset(name)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{}
+
+	for key := range preset.Settings {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	t := table.New(os.Stdout)
+
+	t.SetHeaders("Setting", "Value")
+
+	for _, key := range keys {
+
+		value := preset.Settings[key]
+
+		switch typedValue := value.(type) {
+		case []interface{}:
+			items := []string{}
+
+			if isActiveByDefault(key) || key == "themes" {
+				// Plugins and themes can include objects so they are described the same way as in the config command
+				for _, description := range describePackages(getPackages(typedValue, isActiveByDefault(key)), isActiveByDefault(key)) {
+					items = append(items, console.Bold(description))
+				}
+			} else {
+				for _, item := range typedValue {
+					items = append(items, console.Bold(fmt.Sprint(item)))
+				}
+			}
+
+			t.AddRow(key, strings.Join(items, "\n"))
+		case map[string]interface{}:
+			jsonValue, err := json.Marshal(typedValue)
+			if err != nil {
+				return err
+			}
+
+			t.AddRow(key, console.Bold(string(jsonValue)))
+		default:
+			t.AddRow(key, console.Bold(fmt.Sprint(value)))
+		}
+	}
+
+	t.Render()
+
+	return nil
+}
+
+// GetPreset Returns the preset with the given name
+func (s *Settings) GetPreset(name string) (Preset, error) {
+
+	presets, err := s.getPresets()
+	if err != nil {
+		return Preset{}, err
+	}
+
+	for _, preset := range presets {
+		if preset.Name == name {
+			return preset, nil
+		}
+	}
+
+	return Preset{}, fmt.Errorf("the preset %s does not exist. Use \"kana preset list\" to see the available presets", name)
+}
+
+// SavePreset Saves the settings of a running site as a preset file in the presets folder, returning the file it was saved to
+func (s *Settings) SavePreset(name string, localSettings LocalSettings, force bool) (string, error) {
+
+	if !presetNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid preset name. Please use only letters, numbers, dashes and underscores")
+	}
+
+	presetDirectory := path.Join(s.AppDirectory, presetFolder)
+
+	err := os.MkdirAll(presetDirectory, 0750)
+	if err != nil {
+		return "", err
+	}
+
+	// An existing preset file keeps its format when it is replaced
+	presetFile, err := findConfigFile(presetDirectory, name)
+	if err != nil {
+		return presetFile, err
+	}
+
+	if _, err = os.Stat(presetFile); err == nil && !force {
+		return presetFile, fmt.Errorf("the preset %s already exists. Use the --force flag to replace it", name)
+	}
+
+	values := map[string]interface{}{
+		"php":        localSettings.PHP,
+		"type":       localSettings.Type,
+		"xdebug":     localSettings.Xdebug,
+		"phpmyadmin": localSettings.PhpMyAdmin,
+		"plugins":    getPackagesConfig(localSettings.Plugins, true),
+		"themes":     getPackagesConfig(localSettings.Themes, false),
+	}
+
+	// Options can't be read back from WordPress reliably so they come from the site's config
+	if len(s.Options) > 0 {
+		values["options"] = s.Options
+	}
+
+	// Settings left over from the preset being replaced are removed while any comments in it are kept
+	unset := []string{}
+	presetConfig := viper.New()
+	presetConfig.SetConfigFile(presetFile)
+
+	if presetConfig.ReadInConfig() == nil {
+		for key := range presetConfig.AllSettings() {
+			if _, ok := values[key]; !ok {
+				unset = append(unset, key)
+			}
+		}
+	}
+
+	return presetFile, editConfigFile(presetFile, values, unset)
+}
+
+// getPresetLayer Returns the layer holding the settings from the site's preset, if it has one. Invalid settings are left out and reported when the config is validated.
+func (s *Settings) getPresetLayer() settingLayer {
+
+	layer := settingLayer{}
+
+	if s.Preset == "" {
+		return layer
+	}
+
+	preset, err := s.GetPreset(s.Preset)
+	if err != nil {
+		return layer
+	}
+
+	for key, value := range preset.Settings {
+
+		schema, ok := getSetting(key)
+		if !ok || schema.scope&presetScope == 0 || schema.check(value) != nil {
+			continue
+		}
+
+		layer[key] = SettingValue{
+			Value:  value,
+			Layer:  fmt.Sprintf("preset (%s)", s.Preset),
+			Source: fmt.Sprintf("preset %s in %s", s.Preset, preset.Source),
+		}
+	}
+
+	return layer
+}
+
+// validatePreset Checks the site's preset exists and that any preset files only contain settings that can be used in a preset
+func (s *Settings) validatePreset() error {
+
+	if s.Preset != "" {
+
+		_, err := s.GetPreset(s.Preset)
+		if err != nil {
+			return err
+		}
+	}
+
+	presetFiles, err := s.getPresetFiles()
+	if err != nil {
+		return err
+	}
+
+	configErrors := []string{}
+
+	for _, presetFile := range presetFiles {
+
+		err = validateConfigFile(presetFile, presetScope)
+		if err != nil {
+			configErrors = append(configErrors, err.Error())
+		}
+	}
+
+	if len(configErrors) > 0 {
+		return fmt.Errorf("%s", strings.Join(configErrors, "\n"))
+	}
+
+	return nil
+}
+
+// getPresets Returns every preset sorted by name. Presets saved as files take precedence over presets of the same name in the global config.
+func (s *Settings) getPresets() ([]Preset, error) {
+
+	presets := map[string]Preset{}
+
+	source := s.global.ConfigFileUsed()
+	if _, ok := s.environment["presets"]; ok {
+		source = getEnvName("presets")
+	}
+
+	for name, value := range s.presets {
+
+		presetSettings, ok := value.(map[string]interface{})
+		if !ok {
+			presetSettings = map[string]interface{}{}
+		}
+
+		presets[name] = Preset{
+			Name:     name,
+			Source:   source,
+			Settings: presetSettings,
+		}
+	}
+
+	presetFiles, err := s.getPresetFiles()
+	if err != nil {
+		return []Preset{}, err
+	}
+
+	for _, presetFile := range presetFiles {
+
+		name := strings.TrimSuffix(filepath.Base(presetFile), filepath.Ext(presetFile))
+
+		if existing, ok := presets[name]; ok && existing.Source != source {
+			return []Preset{}, fmt.Errorf("found more than one file for the %s preset: %s and %s. Please remove one of them", name, existing.Source, presetFile)
+		}
+
+		presetConfig := viper.New()
+		presetConfig.SetConfigFile(presetFile)
+
+		err = presetConfig.ReadInConfig()
+		if err != nil {
+			return []Preset{}, fmt.Errorf("%s is invalid: %s", presetFile, err)
+		}
+
+		presets[name] = Preset{
+			Name:     name,
+			Source:   presetFile,
+			Settings: presetConfig.AllSettings(),
+		}
+	}
+
+	sortedPresets := []Preset{}
+
+	for _, preset := range presets {
+		sortedPresets = append(sortedPresets, preset)
+	}
+
+	sort.Slice(sortedPresets, func(i, j int) bool {
+		return sortedPresets[i].Name < sortedPresets[j].Name
+	})
+
+	return sortedPresets, nil
+}
+
+// getPresetFiles Returns the config files in the presets folder
+func (s *Settings) getPresetFiles() ([]string, error) {
+
+	presetFiles := []string{}
+
+	for _, extension := range configExtensions {
+
+		files, err := filepath.Glob(path.Join(s.AppDirectory, presetFolder, fmt.Sprintf("*.%s", extension)))
+		if err != nil {
+			return presetFiles, err
+		}
+
+		presetFiles = append(presetFiles, files...)
+	}
+
+	sort.Strings(presetFiles)
+
+	return presetFiles, nil
+}
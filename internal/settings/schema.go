@@ -0,0 +1,533 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// settingType The type of value a setting holds in a config file
+type settingType string
+
+const (
+	boolSetting    settingType = "a boolean"
+	stringSetting  settingType = "a string"
+	listSetting    settingType = "a list of strings"
+	packageSetting settingType = "a list of plugins or themes"
+	objectSetting  settingType = "an object"
+)
+
+// settingScope The config files a setting can appear in
+type settingScope int
+
+const (
+	globalScope settingScope = 1 << iota
+	localScope
+	presetScope
+	linkScope // A site's link.json, which only holds Kana's own data
+)
+
+// setting Describes a key that can appear in the global or local config files
+type setting struct {
+	key       string
+	valueType settingType
+	scope     settingScope
+	validate  func(value interface{}) error
+	secret    bool // Hidden in output unless the --show-secrets flag is used
+	keepCase  bool // Read from the config file as written as viper lowercases the keys of objects
+}
+
+// ConfigError Lists every problem found in a config file
+type ConfigError struct {
+	File     string
+	Problems []string
+}
+
+var settingsSchema []setting
+
+// init Builds the schema at startup as validating presets refers back to the schema itself
+func init() {
+
+	settingsSchema = getSettingsSchema()
+}
+
+func (e *ConfigError) Error() string {
+
+	return fmt.Sprintf("%s is invalid:\n  %s", e.File, strings.Join(e.Problems, "\n  "))
+}
+
+// ValidateConfig Checks the global config file, the current site's config file, if it has one, any presets and any KANA_* environment variables against the schema. Returns the files that were checked.
+func (s *Settings) ValidateConfig() ([]string, error) {
+
+	configFiles := []string{}
+	configErrors := []string{}
+
+	globalFile := s.global.ConfigFileUsed()
+	localFile := s.local.ConfigFileUsed()
+
+	for _, configFile := range []struct {
+		file  string
+		scope settingScope
+	}{
+		{globalFile, globalScope},
+		{localFile, localScope},
+	} {
+
+		if configFile.file == "" {
+			continue
+		}
+
+		if _, err := os.Stat(configFile.file); os.IsNotExist(err) {
+			continue
+		}
+
+		configFiles = append(configFiles, configFile.file)
+
+		err := validateConfigFile(configFile.file, configFile.scope)
+		if err != nil {
+			configErrors = append(configErrors, err.Error())
+		}
+	}
+
+	err := s.validatePreset()
+	if err != nil {
+		configErrors = append(configErrors, err.Error())
+	}
+
+	err = validateEnvironment()
+	if err != nil {
+		configErrors = append(configErrors, err.Error())
+	}
+
+	configErrors = append(configErrors, s.getMigrationProblems()...)
+
+	// Values that couldn't be applied are only reported when the problems above don't already explain them
+	if len(configErrors) == 0 {
+		configErrors = append(configErrors, s.invalidSettings...)
+	}
+
+	if len(configErrors) > 0 {
+		return configFiles, fmt.Errorf("%s", strings.Join(configErrors, "\n"))
+	}
+
+	return configFiles, nil
+}
+
+// validateConfigFile Reads a config file without any defaults and checks every key in it against the schema
+func validateConfigFile(file string, scope settingScope) error {
+
+	rawConfig := viper.New()
+	rawConfig.SetConfigFile(file)
+
+	err := rawConfig.ReadInConfig()
+	if err != nil {
+		return fmt.Errorf("%s is invalid: %s", file, err)
+	}
+
+	settings := rawConfig.AllSettings()
+
+	for key, value := range getKeepCaseValues(file) {
+		settings[key] = value
+	}
+
+	problems := validateSettings("", settings, scope)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sort.Strings(problems)
+
+	return &ConfigError{
+		File:     file,
+		Problems: problems,
+	}
+}
+
+// validateSettings Checks each key in a (possibly nested) map of settings, returning a description of every problem found
+func validateSettings(prefix string, values map[string]interface{}, scope settingScope) []string {
+
+	problems := []string{}
+
+	for name, value := range values {
+
+		key := name
+		if prefix != "" {
+			key = fmt.Sprintf("%s.%s", prefix, name)
+		}
+
+		// The version is managed by Kana when the file is migrated
+		if key == versionKey && scope&presetScope == 0 {
+			continue
+		}
+
+		schema, ok := getSetting(key)
+		if !ok {
+
+			nestedValues, isMap := value.(map[string]interface{})
+			if isMap && hasNestedSettings(key) {
+				problems = append(problems, validateSettings(key, nestedValues, scope)...)
+				continue
+			}
+
+			problem := fmt.Sprintf("%s: unknown setting", key)
+			if suggestion := suggestSetting(key, scope); suggestion != "" {
+				problem = fmt.Sprintf("%s. Did you mean \"%s\"?", problem, suggestion)
+			}
+
+			problems = append(problems, problem)
+			continue
+		}
+
+		if schema.scope&scope == 0 {
+			problems = append(problems, fmt.Sprintf("%s: can only be set in %s", key, describeScope(schema.scope)))
+			continue
+		}
+
+		err := schema.check(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", key, err))
+		}
+	}
+
+	return problems
+}
+
+// parse Converts a value entered on the command line or in an environment variable to the type the setting holds
+func (s setting) parse(value string) (interface{}, error) {
+
+	switch s.valueType {
+	case boolSetting:
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false", s.key)
+		}
+
+		return boolValue, nil
+	case stringSetting:
+		return value, nil
+	case listSetting, packageSetting:
+		// Plugins and themes can also be entered as a JSON array to include objects marking them active or inactive
+		if s.valueType == packageSetting && strings.HasPrefix(strings.TrimSpace(value), "[") {
+
+			items := []interface{}{}
+
+			err := json.Unmarshal([]byte(value), &items)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a comma separated list or a JSON array", s.key)
+			}
+
+			return items, nil
+		}
+
+		// Lists are entered as comma separated values
+		items := []interface{}{}
+
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		return items, nil
+	}
+
+	// Objects are entered as JSON
+	object := map[string]interface{}{}
+
+	err := json.Unmarshal([]byte(value), &object)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a JSON object", s.key)
+	}
+
+	return object, nil
+}
+
+// check Makes sure a value read from a config file has the right type and passes the setting's validation
+func (s setting) check(value interface{}) error {
+
+	switch s.valueType {
+	case boolSetting:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("expected %s but got %s", s.valueType, describeValue(value))
+		}
+	case stringSetting:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("expected %s but got %s", s.valueType, describeValue(value))
+		}
+	case listSetting:
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected %s but got %s", s.valueType, describeValue(value))
+		}
+
+		for _, item := range items {
+			if _, ok := item.(string); !ok {
+				return fmt.Errorf("expected %s but it contains %s", s.valueType, describeValue(item))
+			}
+		}
+	case packageSetting:
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected %s but got %s", s.valueType, describeValue(value))
+		}
+
+		err := checkPackageList(items)
+		if err != nil {
+			return err
+		}
+	case objectSetting:
+		if _, ok := value.(map[string]interface{}); !ok {
+			return fmt.Errorf("expected %s but got %s", s.valueType, describeValue(value))
+		}
+	}
+
+	if s.validate != nil {
+		return s.validate(value)
+	}
+
+	return nil
+}
+
+// getSettingsSchema Returns every setting Kana understands along with where it can be set and how it is validated
+func getSettingsSchema() []setting {
+
+	schema := []setting{
+		{key: "admin.email", valueType: stringSetting, scope: globalScope, validate: validateTag("email", "a valid email address")},
+		{key: "admin.password", valueType: stringSetting, scope: globalScope, validate: validatePassword, secret: true},
+		{key: "admin.random_password", valueType: boolSetting, scope: globalScope | localScope},
+		{key: "admin.username", valueType: stringSetting, scope: globalScope, validate: validateTag("alpha", "a valid username (letters only)")},
+		{key: "ca.cert", valueType: stringSetting, scope: globalScope, validate: validateTag("omitempty,file", "an existing file")},
+		{key: "ca.domains", valueType: listSetting, scope: globalScope, validate: validateDomains},
+		{key: "ca.key", valueType: stringSetting, scope: globalScope, validate: validateTag("omitempty,file", "an existing file")},
+		{key: "ca.key_type", valueType: stringSetting, scope: globalScope, validate: validateOneOf("key type", validKeyTypes)},
+		{key: "ca.mkcert", valueType: boolSetting, scope: globalScope},
+		{key: "constants", valueType: objectSetting, scope: localScope, validate: validateConstants, keepCase: true},
+		{key: "lan", valueType: boolSetting, scope: globalScope | localScope},
+		{key: "lan_hostname", valueType: stringSetting, scope: globalScope, validate: validateTag("omitempty,fqdn", "a valid hostname")},
+		{key: "local", valueType: boolSetting, scope: globalScope | localScope},
+		{key: "middlewares", valueType: objectSetting, scope: localScope, validate: validateMiddlewares, keepCase: true},
+		{key: "options", valueType: objectSetting, scope: localScope | presetScope},
+		{key: "php_ini", valueType: objectSetting, scope: globalScope | localScope, validate: validatePHPIni},
+		{key: "php", valueType: stringSetting, scope: globalScope | localScope | presetScope, validate: validateOneOf("PHP version", validPHPVersions)},
+		{key: "phpmyadmin", valueType: boolSetting, scope: globalScope | localScope | presetScope},
+		{key: "plugins", valueType: packageSetting, scope: localScope | presetScope, validate: validatePackages},
+		{key: "presets", valueType: objectSetting, scope: globalScope, validate: validatePresets},
+		{key: "ssl", valueType: boolSetting, scope: localScope},
+		{key: "themes", valueType: packageSetting, scope: localScope | presetScope, validate: validateThemes},
+		{key: "type", valueType: stringSetting, scope: globalScope | localScope | presetScope, validate: validateOneOf("type", validTypes)},
+		{key: "wordpress", valueType: stringSetting, scope: globalScope | localScope | presetScope, validate: validateWordPressVersion},
+		{key: "xdebug", valueType: boolSetting, scope: globalScope | localScope | presetScope},
+	}
+
+	for _, name := range ImageNames {
+		schema = append(schema, setting{
+			key:       fmt.Sprintf("images.%s", name),
+			valueType: stringSetting,
+			scope:     globalScope | localScope,
+			validate:  validateImage,
+		})
+	}
+
+	return schema
+}
+
+// getSetting Returns the schema for a key
+func getSetting(key string) (setting, bool) {
+
+	for _, schema := range settingsSchema {
+		if schema.key == key {
+			return schema, true
+		}
+	}
+
+	return setting{}, false
+}
+
+// hasNestedSettings Checks if any settings are nested under the key, such as "admin" or "images"
+func hasNestedSettings(key string) bool {
+
+	for _, schema := range settingsSchema {
+		if strings.HasPrefix(schema.key, fmt.Sprintf("%s.", key)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// suggestSetting Returns the setting with the closest name to an unknown key, if there is one close enough to be a typo
+func suggestSetting(key string, scope settingScope) string {
+
+	suggestion := ""
+	bestDistance := 3
+
+	for _, schema := range settingsSchema {
+
+		if schema.scope&scope == 0 {
+			continue
+		}
+
+		distance := levenshtein(key, schema.key)
+		if distance < bestDistance {
+			suggestion = schema.key
+			bestDistance = distance
+		}
+	}
+
+	return suggestion
+}
+
+// describeScope Describes the config files a setting can appear in for error messages
+func describeScope(scope settingScope) string {
+
+	places := []string{}
+
+	if scope&globalScope != 0 {
+		places = append(places, "the global config")
+	}
+
+	if scope&localScope != 0 {
+		places = append(places, "a site's config file")
+	}
+
+	if scope&presetScope != 0 {
+		places = append(places, "a preset")
+	}
+
+	return strings.Join(places, " or ")
+}
+
+// describeValue Describes the type of a value read from a config file for error messages
+func describeValue(value interface{}) string {
+
+	switch value.(type) {
+	case bool:
+		return fmt.Sprintf("the boolean %v", value)
+	case string:
+		return fmt.Sprintf("the string \"%s\"", value)
+	case int, int64, float64:
+		return fmt.Sprintf("the number %v", value)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "an object"
+	case nil:
+		return "null"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+// validateTag Returns a validator for string settings using a go-playground/validator tag, describing valid values in its errors
+func validateTag(tag, description string) func(value interface{}) error {
+
+	return func(value interface{}) error {
+
+		err := validator.New().Var(value, tag)
+		if err != nil {
+			return fmt.Errorf("\"%s\" is not %s", value, description)
+		}
+
+		return nil
+	}
+}
+
+// validateOneOf Returns a validator for string settings that only allows the given values
+func validateOneOf(name string, validValues []string) func(value interface{}) error {
+
+	return func(value interface{}) error {
+
+		if !isValidString(value.(string), validValues) {
+			return fmt.Errorf("\"%s\" is not a valid %s. Please choose one of: %s", value, name, strings.Join(validValues, ", "))
+		}
+
+		return nil
+	}
+}
+
+// validateImage Checks that an image setting is a valid Docker image reference
+func validateImage(value interface{}) error {
+
+	if !isValidImage(value.(string)) {
+		return fmt.Errorf("\"%s\" is not a valid docker image", value)
+	}
+
+	return nil
+}
+
+// validateWordPressVersion Checks that the wordpress setting is empty, a release channel or a WordPress version number
+func validateWordPressVersion(value interface{}) error {
+
+	version := value.(string)
+
+	if version == "" || isValidString(version, wordPressChannels) || wordPressVersionPattern.MatchString(version) {
+		return nil
+	}
+
+	return fmt.Errorf("\"%s\" is not a valid WordPress version. Please use a version such as 6.1.1 or 6.2-RC1, or one of: %s", version, strings.Join(wordPressChannels, ", "))
+}
+
+// validateMiddlewares Checks that the middlewares object only contains known middlewares with valid values
+func validateMiddlewares(value interface{}) error {
+
+	var middlewares Middlewares
+	var metadata mapstructure.Metadata
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Metadata: &metadata,
+		Result:   &middlewares,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = decoder.Decode(value)
+	if err != nil {
+
+		if decodeErr, ok := err.(*mapstructure.Error); ok {
+			return fmt.Errorf("%s", strings.Join(decodeErr.Errors, "; "))
+		}
+
+		return err
+	}
+
+	if len(metadata.Unused) > 0 {
+		sort.Strings(metadata.Unused)
+		return fmt.Errorf("unknown middleware %s", strings.Join(metadata.Unused, ", "))
+	}
+
+	return middlewares.validate()
+}
+
+// validatePresets Checks that every preset in the global config only contains settings that can be used in a preset
+func validatePresets(value interface{}) error {
+
+	problems := []string{}
+
+	for name, preset := range value.(map[string]interface{}) {
+
+		presetSettings, ok := preset.(map[string]interface{})
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected an object but got %s", name, describeValue(preset)))
+			continue
+		}
+
+		for _, problem := range validateSettings("", presetSettings, presetScope) {
+			problems = append(problems, fmt.Sprintf("%s.%s", name, problem))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package settings
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSuggestSetting(t *testing.T) {
+
+	tests := []struct {
+		key        string
+		scope      settingScope
+		suggestion string
+	}{
+		{"phpp", localScope, "php"},
+		{"xdbug", globalScope, "xdebug"},
+		{"admin.emial", globalScope, "admin.email"},
+		{"admin.emial", localScope, ""},
+		{"plugin", presetScope, "plugins"},
+		{"database", localScope, ""},
+	}
+
+	for _, test := range tests {
+
+		suggestion := suggestSetting(test.key, test.scope)
+		if suggestion != test.suggestion {
+			t.Errorf("Expected the suggestion for %s to be %q; got %q", test.key, test.suggestion, suggestion)
+		}
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		values   map[string]interface{}
+		scope    settingScope
+		problems []string
+	}{
+		{
+			"valid settings",
+			map[string]interface{}{"php": "8.1", "xdebug": true, "plugins": []interface{}{"akismet"}, "version": 1},
+			localScope,
+			[]string{},
+		},
+		{
+			"misspelled setting",
+			map[string]interface{}{"phpp": "8.1"},
+			localScope,
+			[]string{`phpp: unknown setting. Did you mean "php"?`},
+		},
+		{
+			"unknown setting",
+			map[string]interface{}{"database": "mysql"},
+			localScope,
+			[]string{"database: unknown setting"},
+		},
+		{
+			"setting in the wrong file",
+			map[string]interface{}{"admin": map[string]interface{}{"username": "kana"}},
+			localScope,
+			[]string{"admin.username: can only be set in the global config"},
+		},
+		{
+			"wrong type",
+			map[string]interface{}{"xdebug": "yes", "plugins": "akismet"},
+			localScope,
+			[]string{
+				`plugins: expected a list of plugins or themes but got the string "akismet"`,
+				`xdebug: expected a boolean but got the string "yes"`,
+			},
+		},
+		{
+			"invalid value",
+			map[string]interface{}{"php": "5.6"},
+			globalScope,
+			[]string{`php: "5.6" is not a valid PHP version. Please choose one of: 7.4, 8.0, 8.1, 8.2`},
+		},
+	}
+
+	for _, test := range tests {
+
+		problems := validateSettings("", test.values, test.scope)
+		sort.Strings(problems)
+
+		if !reflect.DeepEqual(problems, test.problems) {
+			t.Errorf("%s: expected %q; got %q", test.name, test.problems, problems)
+		}
+	}
+}
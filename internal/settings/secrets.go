@@ -0,0 +1,130 @@
+package settings
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/viper"
+)
+
+// Config files can hold credentials such as the admin password so only the current user can read them
+const configPermissions os.FileMode = 0600
+
+// Shown in place of secrets unless the --show-secrets flag is used
+const redactedSecret = "********"
+
+// The characters used in generated passwords. Symbols are included as the password never has to be typed on a command line.
+const passwordCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!#$%&()*+,-./:;<=>?@[]^_{|}~"
+
+// The length of generated admin passwords
+const passwordLength = 24
+
+// RedactSecret Hides a secret such as a password for display unless the --show-secrets flag was used
+func (s *Settings) RedactSecret(secret string) string {
+
+	if s.ShowSecrets || secret == "" {
+		return secret
+	}
+
+	return redactedSecret
+}
+
+// GeneratePassword Returns a random password for a site's admin user
+func GeneratePassword() (string, error) {
+
+	password := make([]byte, passwordLength)
+	max := big.NewInt(int64(len(passwordCharacters)))
+
+	for i := range password {
+
+		index, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		password[i] = passwordCharacters[index.Int64()]
+	}
+
+	return string(password), nil
+}
+
+// redactSetting Hides any secrets in a setting's value for display, including the passwords of basic auth users
+func (s *Settings) redactSetting(key string, value interface{}) interface{} {
+
+	schema, _ := getSetting(key)
+
+	if secret, ok := value.(string); ok && schema.secret {
+		return s.RedactSecret(secret)
+	}
+
+	middlewares, ok := value.(map[string]interface{})
+	if key != "middlewares" || !ok || s.ShowSecrets {
+		return value
+	}
+
+	users, ok := middlewares["basic_auth"].([]interface{})
+	if !ok {
+		return value
+	}
+
+	redacted := map[string]interface{}{}
+
+	for name, middleware := range middlewares {
+		redacted[name] = middleware
+	}
+
+	redactedUsers := []interface{}{}
+
+	for _, user := range users {
+
+		if userString, ok := user.(string); ok {
+			name, password, _ := strings.Cut(userString, ":")
+			user = fmt.Sprintf("%s:%s", name, s.RedactSecret(password))
+		}
+
+		redactedUsers = append(redactedUsers, user)
+	}
+
+	redacted["basic_auth"] = redactedUsers
+
+	return redacted
+}
+
+// writeConfig Writes a config file that only the current user can read. Viper only sets the permissions of new files so existing files are changed as well.
+func writeConfig(config *viper.Viper, configFile string) error {
+
+	config.SetConfigPermissions(configPermissions)
+
+	err := config.WriteConfigAs(configFile)
+	if err != nil {
+		return err
+	}
+
+	return os.Chmod(configFile, configPermissions)
+}
+
+// validatePassword Allows any password that fits on a single line, including symbols
+func validatePassword(value interface{}) error {
+
+	password := value.(string)
+
+	if password == "" {
+		return fmt.Errorf("the password can't be empty")
+	}
+
+	if strings.TrimSpace(password) != password {
+		return fmt.Errorf("the password can't start or end with spaces")
+	}
+
+	for _, character := range password {
+		if unicode.IsControl(character) {
+			return fmt.Errorf("the password can't contain new lines or other control characters")
+		}
+	}
+
+	return nil
+}
@@ -1,8 +1,13 @@
 package settings
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/ChrisWiegman/kana-cli/pkg/minica"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
@@ -18,26 +23,134 @@ var (
 	configFolderName = ".config/kana"
 	php              = "8.1"
 	siteType         = "site"
+	wordPress        = ""
 	xdebug           = false
 	phpmyadmin       = false
 	local            = false
+	lan              = false
+	ssl              = true
+	lanHostname      = ""
+	lanCert          = "kana.lan.pem"
+	lanKey           = "kana.lan.key"
+	caKeyType        = minica.KeyTypeECDSA
 	adminUsername    = "admin"
 	adminPassword    = "password"
+	randomPassword   = false
 	adminEmail       = "admin@sites.kana.li"
 )
 
+// The names the root CA is installed under in system and browser trust stores
+var (
+	rootCertName     = "Kana Development CA"
+	rootCertFileName = "kana-development-ca"
+)
+
+// The formats config files can be written in, in order of preference
+var configExtensions = []string{
+	"json",
+	"yaml",
+	"yml",
+	"toml",
+}
+
+// Presets can also be saved as individual files in this folder inside the app directory
+var presetFolder = "presets"
+
+// Preset names are used as file names so they are limited to letters, numbers, dashes and underscores
+var presetNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Each site's own certificate is kept in this folder inside the certs folder
+var siteCertFolder = "sites"
+
+// Certificates are reissued when they are this close to expiring
+var certRenewalWindow = 30 * 24 * time.Hour
+
+// The default image catalogue. "{php}" is replaced with the site's PHP version.
+var defaultImages = map[string]string{
+	"traefik":    "traefik:v2.9.6",
+	"mariadb":    "mariadb:10.10.2",
+	"phpmyadmin": "phpmyadmin:5.2.0",
+	"wordpress":  "wordpress:6.1.1-php{php}",
+	"wpcli":      "wordpress:cli-2.7.1-php{php}",
+}
+
+// ImageNames The names of all images in the catalogue in the order they should be displayed
+var ImageNames = []string{
+	"traefik",
+	"mariadb",
+	"phpmyadmin",
+	"wordpress",
+	"wpcli",
+}
+
+// getDefaultSettings Returns the value of every setting that isn't set in a config file, preset, environment variable or flag
+func getDefaultSettings() map[string]interface{} {
+
+	defaults := map[string]interface{}{
+		"admin.email":           adminEmail,
+		"admin.password":        adminPassword,
+		"admin.random_password": randomPassword,
+		"admin.username":        adminUsername,
+		"ca.cert":               "",
+		"ca.domains":            []interface{}{},
+		"ca.key":                "",
+		"ca.key_type":           caKeyType,
+		"ca.mkcert":             false,
+		"constants":             map[string]interface{}{},
+		"lan":                   lan,
+		"lan_hostname":          lanHostname,
+		"local":                 local,
+		"middlewares":           map[string]interface{}{},
+		"options":               map[string]interface{}{},
+		"php":                   php,
+		"php_ini":               map[string]interface{}{},
+		"phpmyadmin":            phpmyadmin,
+		"plugins":               []interface{}{},
+		"ssl":                   ssl,
+		"themes":                []interface{}{},
+		"type":                  siteType,
+		"wordpress":             wordPress,
+		"xdebug":                xdebug,
+	}
+
+	for name, image := range defaultImages {
+		defaults[fmt.Sprintf("images.%s", name)] = image
+	}
+
+	return defaults
+}
+
 // Individual Settings for use throughout the app lifecycle
 type Settings struct {
-	Local, PhpMyAdmin, Xdebug                     bool
+	Lan, Local, PhpMyAdmin, SSL, Xdebug           bool
 	AdminEmail, AdminPassword, AdminUsername      string
+	AdminRandomPassword, ShowSecrets              bool
+	SkipMigrations                                bool
 	AppDirectory, SiteDirectory, WorkingDirectory string
 	AppDomain, SiteDomain                         string
+	LanHostname                                   string
 	Name                                          string
-	PHP                                           string
+	PHP, WordPress                                string
 	RootCert, RootKey, SiteCert, SiteKey          string
+	LanCert, LanKey                               string
+	CACert, CAKey                                 string
+	CADomains                                     []string
+	CAKeyType                                     string
+	CAMkcert                                      bool
 	SecureURL, URL                                string
 	Type                                          string
-	Plugins                                       []string
+	Plugins, Themes                               []Package
+	Options                                       map[string]interface{}
+	PHPIni                                        map[string]interface{}
+	Constants                                     map[string]interface{}
+	Preset                                        string
+	Images                                        map[string]string
+	Middlewares                                   Middlewares
+	environment                                   map[string]interface{}
+	presets                                       map[string]interface{}
+	pendingMigrations                             []pendingMigration
+	invalidSettings                               []string
+	layers                                        map[layerRank]settingLayer
 	global                                        *viper.Viper
 	local                                         *viper.Viper
 }
@@ -49,6 +162,19 @@ var validPHPVersions = []string{
 	"8.2",
 }
 
+var validKeyTypes = []string{
+	minica.KeyTypeECDSA,
+	minica.KeyTypeRSA,
+}
+
+// WordPress versions can be a release such as 6.1 or 6.1.1, a beta or release candidate such as 6.2-beta1 or 6.2-RC1, or one of these
+var wordPressChannels = []string{
+	"latest",
+	"nightly",
+}
+
+var wordPressVersionPattern = regexp.MustCompile(`^\d+\.\d+(\.\d+)?(-(beta|RC)\d+)?$`)
+
 var validTypes = []string{
 	"site",
 	"plugin",
@@ -64,6 +190,8 @@ func NewSettings() (*Settings, error) {
 	kanaSettings.RootCert = rootCert
 	kanaSettings.SiteCert = siteCert
 	kanaSettings.SiteKey = siteKey
+	kanaSettings.LanCert = lanCert
+	kanaSettings.LanKey = lanKey
 
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -0,0 +1,133 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+)
+
+// WordPress only reads this much of a file when looking for plugin and theme headers
+const headerSize = 8192
+
+// InitConfig Creates the config file for the current site from the detected site type and the user's answers, or from the defaults when acceptDefaults is true. Returns the file that was written.
+func (s *Settings) InitConfig(acceptDefaults bool) (string, error) {
+
+	configFile := s.GetConfigFile(true)
+
+	if _, err := os.Stat(configFile); err == nil {
+		return configFile, fmt.Errorf("%s already exists. Use \"kana config edit --local\" to change it", configFile)
+	}
+
+	// Environment variables and flags only apply to this run so they are offered as answers but never saved without asking
+	saved := Settings{Images: map[string]string{}, layers: s.layers}
+	saved.applySettings(presetLayer)
+
+	localSettings := LocalSettings{
+		Lan:        saved.Lan,
+		Local:      saved.Local,
+		PhpMyAdmin: saved.PhpMyAdmin,
+		SSL:        saved.SSL,
+		Xdebug:     saved.Xdebug,
+		PHP:        saved.PHP,
+		Type:       detectSiteType(s.WorkingDirectory),
+		Plugins:    saved.Plugins,
+		Themes:     saved.Themes,
+	}
+
+	if localSettings.Type != "site" {
+		console.Println(fmt.Sprintf("This folder looks like a WordPress %s.", localSettings.Type))
+	}
+
+	if !acceptDefaults {
+
+		localSettings.Type = promptSetting(fmt.Sprintf("Is this a site, plugin or theme? (%s)", strings.Join(validTypes, ", ")), "type", localSettings.Type).(string)
+		localSettings.PHP = promptSetting(fmt.Sprintf("Which PHP version should the site use? (%s)", strings.Join(validPHPVersions, ", ")), "php", s.PHP).(string)
+
+		pluginSources := []string{}
+
+		for _, plugin := range s.Plugins {
+			pluginSources = append(pluginSources, plugin.Source)
+		}
+
+		localSettings.Plugins = getPackages(promptSetting("Which plugins should be installed? Enter their WordPress.org slugs, zip file URLs or paths separated by commas.", "plugins", strings.Join(pluginSources, ",")), true)
+
+		localSettings.Xdebug = console.PromptConfirm("Would you like to enable Xdebug?", s.Xdebug)
+		localSettings.PhpMyAdmin = console.PromptConfirm("Would you like to enable phpMyAdmin?", s.PhpMyAdmin)
+		localSettings.Local = console.PromptConfirm("Would you like the WordPress files in a \"wordpress\" folder here instead of Kana's app directory?", s.Local)
+	}
+
+	err := s.WriteLocalSettings(localSettings)
+	if err != nil {
+		return configFile, err
+	}
+
+	// Never leave a config file behind that would stop the site from loading
+	err = validateConfigFile(configFile, localScope)
+	if err != nil {
+		_ = os.Remove(configFile)
+		return configFile, err
+	}
+
+	return configFile, nil
+}
+
+// promptSetting Asks the user for the value of a setting until they enter one that is valid
+func promptSetting(promptText, key, def string) interface{} {
+
+	schema, _ := getSetting(key)
+
+	for {
+
+		value, err := schema.parse(console.PromptString(promptText, def))
+		if err == nil {
+			err = schema.check(value)
+		}
+
+		if err == nil {
+			return value
+		}
+
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+	}
+}
+
+// detectSiteType Checks the headers WordPress uses to recognize themes and plugins to tell what type of site a folder holds
+func detectSiteType(dir string) string {
+
+	if hasHeader(path.Join(dir, "style.css"), "Theme Name:") {
+		return "theme"
+	}
+
+	phpFiles, err := filepath.Glob(path.Join(dir, "*.php"))
+	if err != nil {
+		return "site"
+	}
+
+	for _, phpFile := range phpFiles {
+		if hasHeader(phpFile, "Plugin Name:") {
+			return "plugin"
+		}
+	}
+
+	return "site"
+}
+
+// hasHeader Checks if a file contains a WordPress file header within the part of the file WordPress reads
+func hasHeader(file, header string) bool {
+
+	contents, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer contents.Close()
+
+	buffer := make([]byte, headerSize)
+
+	length, _ := contents.Read(buffer)
+
+	return strings.Contains(string(buffer[:length]), header)
+}
@@ -0,0 +1,43 @@
+package settings
+
+import (
+	"path"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestInitConfigLeavesOutEnvironment(t *testing.T) {
+
+	workingDirectory := t.TempDir()
+
+	local := viper.New()
+	local.SetConfigFile(path.Join(workingDirectory, ".kana.json"))
+
+	settings := Settings{
+		WorkingDirectory: workingDirectory,
+		Images:           map[string]string{},
+		local:            local,
+		layers: map[layerRank]settingLayer{
+			defaultLayer:     getDefaultLayer(),
+			globalLayer:      {"php": {Value: "8.1", Layer: "global", Source: "kana.json"}},
+			environmentLayer: {"php": {Value: "8.2", Layer: "environment", Source: "KANA_PHP"}, "xdebug": {Value: true, Layer: "environment", Source: "KANA_XDEBUG"}},
+		},
+	}
+
+	settings.applySettings(layerCount)
+
+	configFile, err := settings.InitConfig(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := readConfigFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if values["php"] != "8.1" || values["xdebug"] != false {
+		t.Errorf("Expected the global php and default xdebug values to be saved; got php %v and xdebug %v", values["php"], values["xdebug"])
+	}
+}
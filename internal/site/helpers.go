@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
 )
 
 // arrayContains Searches an array of strings for a given string and returns true/false as appropriate
@@ -17,6 +20,18 @@ func arrayContains(array []string, name string) bool {
 	return false
 }
 
+// isConfigCommand Checks if the command is "config", "preset" or one of their subcommands
+func isConfigCommand(cmd *cobra.Command) bool {
+
+	for _, use := range []string{"config", "preset"} {
+		if cmd.Use == use || (cmd.HasParent() && cmd.Parent().Use == use) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // copyFile Copies a file on the user's host from one place to another
 func copyFile(src, dest string) error {
 
@@ -45,3 +60,15 @@ func copyFile(src, dest string) error {
 	_, err = io.Copy(destination, source)
 	return err
 }
+
+// quoteShellArgs Quotes arguments so that a shell passes them to a command exactly as they are
+func quoteShellArgs(args []string) string {
+
+	quoted := []string{}
+
+	for _, arg := range args {
+		quoted = append(quoted, fmt.Sprintf("'%s'", strings.ReplaceAll(arg, "'", `'\''`)))
+	}
+
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,94 @@
+package site
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ChrisWiegman/kana-cli/internal/settings"
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+
+	"github.com/aquasecurity/table"
+	"github.com/logrusorgru/aurora/v4"
+)
+
+// ListImages Lists every image in the catalogue and whether it has been pulled
+func (s *Site) ListImages() error {
+
+	t := table.New(os.Stdout)
+
+	t.SetHeaders("Name", "Image", "Pulled")
+
+	for _, name := range settings.ImageNames {
+
+		image := s.getImage(name)
+
+		exists, err := s.dockerClient.ImageExists(image)
+		if err != nil {
+			return err
+		}
+
+		pulled := "no"
+		if exists {
+			pulled = "yes"
+		}
+
+		t.AddRow(name, console.Bold(image), pulled)
+	}
+
+	t.Render()
+
+	return nil
+}
+
+// PullImages Pulls any images in the catalogue that aren't already available locally
+func (s *Site) PullImages() error {
+
+	for _, name := range settings.ImageNames {
+
+		image := s.getImage(name)
+
+		console.Println(fmt.Sprintf("Ensuring image: %s", aurora.Bold(aurora.Blue(image))))
+
+		err := s.dockerClient.EnsureImage(image)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateImages Removes and re-pulls every image in the catalogue to pick up changes to their tags
+func (s *Site) UpdateImages() error {
+
+	for _, name := range settings.ImageNames {
+
+		image := s.getImage(name)
+
+		console.Println(fmt.Sprintf("Updating image: %s", aurora.Bold(aurora.Blue(image))))
+
+		// Images used by a running site can't be removed. Keep the current copy and move on.
+		_, err := s.dockerClient.RemoveImage(image)
+		if err != nil {
+			console.Warn(fmt.Sprintf("Unable to update %s. Stop any sites using it and try again.", aurora.Bold(aurora.Blue(image))))
+			continue
+		}
+
+		err = s.dockerClient.EnsureImage(image)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getImage Returns the image for a catalogue entry, using the tag for the site's WordPress version for the WordPress image
+func (s *Site) getImage(name string) string {
+
+	if name == "wordpress" {
+		return s.Settings.GetWordPressImage()
+	}
+
+	return s.Settings.GetImage(name)
+}
@@ -0,0 +1,164 @@
+package site
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/logrusorgru/aurora/v4"
+)
+
+type accessLogEntry struct {
+	RouterName       string    `json:"RouterName"`
+	RequestMethod    string    `json:"RequestMethod"`
+	RequestPath      string    `json:"RequestPath"`
+	DownstreamStatus int       `json:"DownstreamStatus"`
+	Duration         int64     `json:"Duration"`
+	StartLocal       time.Time `json:"StartLocal"`
+}
+
+var validStatusFilter = regexp.MustCompile(`^[1-5]([0-9]{2}|xx)$`)
+
+// ShowRequests Prints the requests Traefik has logged for the current site, optionally waiting for new ones
+func (s *Site) ShowRequests(follow bool, status string) error {
+
+	status = strings.ToLower(status)
+
+	if status != "" && !validStatusFilter.MatchString(status) {
+		return fmt.Errorf("invalid status filter %q. Please use a status code such as 404 or a class such as 5xx", status)
+	}
+
+	logFile := path.Join(s.Settings.AppDirectory, "logs", "traefik", "access.log")
+
+	file, err := os.Open(logFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no requests have been logged yet. Please make sure the site is running")
+		}
+
+		return err
+	}
+
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	partialLine := ""
+
+	for {
+
+		line, err := reader.ReadString('\n')
+
+		if err == io.EOF {
+
+			if !follow {
+				return nil
+			}
+
+			// Keep anything we've read of an unfinished line until Traefik writes the rest of it
+			partialLine += line
+			time.Sleep(500 * time.Millisecond)
+
+			// Start again from the top of the log once it has been cleared
+			if isLogTruncated(file) {
+				_, err = file.Seek(0, io.SeekStart)
+				if err != nil {
+					return err
+				}
+
+				reader.Reset(file)
+				partialLine = ""
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		line = partialLine + line
+		partialLine = ""
+
+		var entry accessLogEntry
+
+		// Skip anything that isn't a valid access log entry
+		if json.Unmarshal([]byte(line), &entry) != nil {
+			continue
+		}
+
+		if !s.isSiteRequest(entry) || !matchesStatus(entry.DownstreamStatus, status) {
+			continue
+		}
+
+		printRequest(entry)
+	}
+}
+
+// isLogTruncated Checks if the log has become shorter than what has already been read from it
+func isLogTruncated(file *os.File) bool {
+
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false
+	}
+
+	logInfo, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return logInfo.Size() < offset
+}
+
+// isSiteRequest Checks if a logged request was handled by one of the current site's WordPress routers
+func (s *Site) isSiteRequest(entry accessLogEntry) bool {
+
+	routerName := strings.TrimSuffix(entry.RouterName, "@docker")
+
+	return routerName == fmt.Sprintf("wordpress-%s", s.Settings.Name) || routerName == fmt.Sprintf("wordpress-%s-http", s.Settings.Name)
+}
+
+// matchesStatus Checks a status code against a filter such as "404" or "5xx"
+func matchesStatus(code int, status string) bool {
+
+	if status == "" {
+		return true
+	}
+
+	if strings.HasSuffix(status, "xx") {
+		return strconv.Itoa(code/100) == status[:1]
+	}
+
+	return strconv.Itoa(code) == status
+}
+
+// printRequest Prints a single request to the console
+func printRequest(entry accessLogEntry) {
+
+	status := aurora.Green(entry.DownstreamStatus)
+
+	switch {
+	case entry.DownstreamStatus >= 500:
+		status = aurora.Red(entry.DownstreamStatus)
+	case entry.DownstreamStatus >= 400:
+		status = aurora.Yellow(entry.DownstreamStatus)
+	case entry.DownstreamStatus >= 300:
+		status = aurora.Blue(entry.DownstreamStatus)
+	}
+
+	duration := time.Duration(entry.Duration).Round(time.Millisecond)
+
+	fmt.Printf("%s  %-7s %d %8s  %s\n",
+		entry.StartLocal.Format("2006-01-02 15:04:05"),
+		entry.RequestMethod,
+		aurora.Bold(status),
+		duration,
+		entry.RequestPath)
+}
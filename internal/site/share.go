@@ -0,0 +1,132 @@
+package site
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
+	"github.com/logrusorgru/aurora/v4"
+	"github.com/mdp/qrterminal/v3"
+)
+
+var shareLandingPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Kana: %[1]s</title>
+</head>
+<body>
+<h1>%[1]s</h1>
+<p>Install and trust the Kana Development CA on this device before opening the site.</p>
+<ul>
+<li><a href="/kana-root-ca.crt">Download the Kana root CA (iOS and Android)</a></li>
+<li><a href="/kana-root-ca.pem">Download the Kana root CA (PEM)</a></li>
+<li><a href="%[2]s">Open %[1]s</a></li>
+</ul>
+</body>
+</html>
+`
+
+// ShareSite Prints a QR code for devices on the local network and serves the root CA until interrupted
+func (s *Site) ShareSite(port int) error {
+
+	lanSiteDomain, err := s.getSharedSiteDomain()
+	if err != nil {
+		return err
+	}
+
+	rootCertPath, err := s.Settings.GetRootCertPath()
+	if err != nil {
+		return err
+	}
+
+	rootCert, err := os.ReadFile(rootCertPath)
+	if err != nil {
+		return err
+	}
+
+	block, _ := pem.Decode(rootCert)
+	if block == nil {
+		return fmt.Errorf("unable to read the Kana root CA")
+	}
+
+	lanDomain, err := s.Settings.GetLANDomain()
+	if err != nil {
+		return err
+	}
+
+	// Always serve the CA from an address the device can reach without DNS
+	host := lanDomain
+	if strings.HasSuffix(lanDomain, ".nip.io") {
+		host = strings.TrimSuffix(lanDomain, ".nip.io")
+	}
+
+	siteURL := fmt.Sprintf("%s://%s/", s.getURLScheme(), lanSiteDomain)
+	shareURL := fmt.Sprintf("http://%s/", net.JoinHostPort(host, fmt.Sprint(port)))
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, shareLandingPage, lanSiteDomain, siteURL)
+	})
+
+	mux.HandleFunc("/kana-root-ca.crt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-x509-ca-cert")
+		w.Write(block.Bytes)
+	})
+
+	mux.HandleFunc("/kana-root-ca.pem", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		w.Write(rootCert)
+	})
+
+	console.Println(fmt.Sprintf("Your site is available on your local network at %s", aurora.Bold(aurora.Green(siteURL))))
+	console.Println(fmt.Sprintf("Scan the code below or visit %s on your device to trust the Kana root CA and open the site.\n", aurora.Bold(aurora.Blue(shareURL))))
+
+	qrterminal.GenerateHalfBlock(shareURL, qrterminal.L, os.Stdout)
+
+	console.Println("\nPress Ctrl+C to stop sharing.")
+
+	return http.ListenAndServe(fmt.Sprintf(":%d", port), mux)
+}
+
+// getLANConfigExtra Returns PHP for WORDPRESS_CONFIG_EXTRA that keeps WordPress on the LAN domain when it is used to load the site
+func getLANConfigExtra(lanSiteDomain, scheme string) string {
+
+	return fmt.Sprintf(
+		"if (isset($_SERVER['HTTP_HOST']) && $_SERVER['HTTP_HOST'] === '%[1]s') { define('WP_HOME', '%[2]s://%[1]s'); define('WP_SITEURL', '%[2]s://%[1]s'); }",
+		lanSiteDomain, scheme)
+}
+
+// getLANSiteDomain Returns the domain the current site is reachable at from other devices on the local network
+func (s *Site) getLANSiteDomain() (string, error) {
+
+	lanDomain, err := s.Settings.GetLANDomain()
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s.%s", s.Settings.Name, lanDomain), nil
+}
+
+// getSharedSiteDomain Returns the LAN domain the running site was started with
+func (s *Site) getSharedSiteDomain() (string, error) {
+
+	containers, err := s.dockerClient.ListContainers(s.Settings.Name)
+	if err != nil {
+		return "", err
+	}
+
+	for _, container := range containers {
+		if lanSiteDomain, ok := container.Labels["kana.lan"]; ok {
+			return lanSiteDomain, nil
+		}
+	}
+
+	return "", fmt.Errorf("the site was not started with LAN sharing. Please restart it with 'kana start --lan' or set the lan setting")
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/ChrisWiegman/kana-cli/internal/settings"
 	"github.com/ChrisWiegman/kana-cli/pkg/console"
 	"github.com/ChrisWiegman/kana-cli/pkg/docker"
+	"github.com/aquasecurity/table"
 	"github.com/logrusorgru/aurora/v4"
 	"github.com/pkg/browser"
 	"github.com/spf13/cobra"
@@ -50,6 +51,17 @@ func (s *Site) ExportSiteConfig() error {
 	return s.Settings.WriteLocalSettings(localSettings)
 }
 
+// SavePreset Saves the current running config as a preset that can be used to start other sites
+func (s *Site) SavePreset(name string, force bool) (string, error) {
+
+	localSettings, err := s.getRunningConfig(true)
+	if err != nil {
+		return "", err
+	}
+
+	return s.Settings.SavePreset(name, localSettings, force)
+}
+
 // IsSiteRunning Returns true if the site is up and running in Docker or false. Does not verify other errors
 func (s *Site) IsSiteRunning() bool {
 
@@ -67,6 +79,12 @@ func (s *Site) LoadSite(cmd *cobra.Command, commandsRequiringSite []string, star
 		return err
 	}
 
+	// Secrets such as the admin password are hidden in all output unless they are asked for
+	s.Settings.ShowSecrets, _ = cmd.Flags().GetBool("show-secrets")
+
+	// The config and preset commands only upgrade old config files when asked to with `kana config migrate`
+	s.Settings.SkipMigrations = isConfigCommand(cmd)
+
 	// Load app-wide settings
 	err = s.Settings.LoadGlobalSettings()
 	if err != nil {
@@ -79,6 +97,17 @@ func (s *Site) LoadSite(cmd *cobra.Command, commandsRequiringSite []string, star
 		return err
 	}
 
+	// The config and preset commands validate the files themselves so that broken files can still be inspected and fixed.
+	// They still warn about invalid environment variables so an override that is being ignored isn't missed.
+	if !isConfigCommand(cmd) {
+		_, err = s.Settings.ValidateConfig()
+		if err != nil {
+			return err
+		}
+	} else if cmd.Use != "validate" && cmd.Use != "migrate" {
+		s.Settings.WarnConfigProblems()
+	}
+
 	// Fail now if we have a command that requires a completed site and we haven't started it before
 	if !isSite && arrayContains(commandsRequiringSite, cmd.Use) {
 		return fmt.Errorf("the current site you are trying to work with does not exist. Use `kana start` to initialize")
@@ -93,6 +122,11 @@ func (s *Site) LoadSite(cmd *cobra.Command, commandsRequiringSite []string, star
 		}
 
 		s.Settings.ProcessStartFlags(cmd, startFlags)
+
+		// Show where the settings that were changed from their defaults came from to help debug why a site started the way it did
+		if flagVerbose {
+			s.Settings.PrintChangedSettingOrigins()
+		}
 	}
 
 	return nil
@@ -107,21 +141,71 @@ func (s *Site) OpenSite() error {
 	}
 
 	if runtime.GOOS == "linux" {
-		openCmd := exec.Command("xdg-open", s.Settings.SecureURL)
+		openCmd := exec.Command("xdg-open", s.getSiteURL())
 		return openCmd.Run()
 	}
 
-	return browser.OpenURL(s.Settings.SecureURL)
+	return browser.OpenURL(s.getSiteURL())
+}
+
+// PrintSiteInfo Prints a summary of the current site including the version of WordPress it is running
+func (s *Site) PrintSiteInfo() error {
+
+	running := s.IsSiteRunning()
+
+	wordPressVersion := "Start the site to check the installed version"
+	if running {
+
+		version, err := s.getWordPressVersion()
+		if err != nil {
+			return err
+		}
+
+		wordPressVersion = version
+	}
+
+	requestedVersion := s.Settings.WordPress
+	if requestedVersion == "" {
+		requestedVersion = "The version in the image"
+	}
+
+	preset := s.Settings.Preset
+	if preset == "" {
+		preset = "None"
+	}
+
+	t := table.New(os.Stdout)
+
+	t.SetHeaders("Setting", "Value")
+
+	t.AddRow("Name", console.Bold(s.Settings.Name))
+	t.AddRow("URL", console.Bold(s.getSiteURL()))
+	t.AddRow("Running", console.Bold(strconv.FormatBool(running)))
+	t.AddRow("Directory", console.Bold(s.Settings.WorkingDirectory))
+	t.AddRow("Type", console.Bold(s.Settings.Type))
+	t.AddRow("PHP", console.Bold(s.Settings.PHP))
+	t.AddRow("WordPress", console.Bold(wordPressVersion))
+	t.AddRow("WordPress (config)", console.Bold(requestedVersion))
+	t.AddRow("WordPress image", console.Bold(s.getImage("wordpress")))
+	t.AddRow("Preset", console.Bold(preset))
+
+	t.Render()
+
+	return nil
 }
 
 // PrintSiteSettings Prints all current site settings to the console for debugging
 func (s *Site) PrintSiteSettings() {
 
 	fmt.Printf("Local: %s\n", strconv.FormatBool(s.Settings.Local))
+	fmt.Printf("Lan: %s\n", strconv.FormatBool(s.Settings.Lan))
+	fmt.Printf("LanHostname: %s\n", s.Settings.LanHostname)
 	fmt.Printf("Xdebug: %s\n", strconv.FormatBool(s.Settings.Xdebug))
 	fmt.Printf("PhpMyAdmin: %s\n", strconv.FormatBool(s.Settings.PhpMyAdmin))
+	fmt.Printf("SSL: %s\n", strconv.FormatBool(s.Settings.SSL))
 	fmt.Printf("AdminEmail: %s\n", s.Settings.AdminEmail)
-	fmt.Printf("AdminPassword: %s\n", s.Settings.AdminPassword)
+	fmt.Printf("AdminPassword: %s\n", s.Settings.RedactSecret(s.Settings.AdminPassword))
+	fmt.Printf("AdminRandomPassword: %s\n", strconv.FormatBool(s.Settings.AdminRandomPassword))
 	fmt.Printf("AdminUsername: %s\n", s.Settings.AdminUsername)
 	fmt.Printf("AppDirectory: %s\n", s.Settings.AppDirectory)
 	fmt.Printf("SiteDirectory: %s\n", s.Settings.SiteDirectory)
@@ -138,7 +222,11 @@ func (s *Site) PrintSiteSettings() {
 	fmt.Printf("Type: %s\n", s.Settings.Type)
 
 	for _, plugin := range s.Settings.Plugins {
-		fmt.Printf("Plugin: %s\n", plugin)
+		fmt.Printf("Plugin: %s (active: %s)\n", plugin.Source, strconv.FormatBool(plugin.Active))
+	}
+
+	for _, theme := range s.Settings.Themes {
+		fmt.Printf("Theme: %s (active: %s)\n", theme.Source, strconv.FormatBool(theme.Active))
 	}
 }
 
@@ -146,7 +234,7 @@ func (s *Site) PrintSiteSettings() {
 func (s *Site) StartSite() error {
 
 	// Let's start everything up
-	fmt.Printf("Starting development site: %s\n", aurora.Bold(aurora.Green(s.getSiteURL(false))))
+	fmt.Printf("Starting development site: %s\n", aurora.Bold(aurora.Green(s.getSiteURL())))
 
 	// Start Traefik if we need it
 	err := s.startTraefik()
@@ -172,6 +260,18 @@ func (s *Site) StartSite() error {
 		return err
 	}
 
+	// Install the WordPress version from the config if the image didn't come with it
+	err = s.ensureWordPressVersion()
+	if err != nil {
+		return err
+	}
+
+	// Add the constants from the config to wp-config.php
+	err = s.updateConstants()
+	if err != nil {
+		return err
+	}
+
 	// Install Xdebug if we need to
 	_, err = s.installXdebug()
 	if err != nil {
@@ -184,6 +284,18 @@ func (s *Site) StartSite() error {
 		return err
 	}
 
+	// Install any configuration themes if needed
+	err = s.installDefaultThemes()
+	if err != nil {
+		return err
+	}
+
+	// Set any WordPress options from the config
+	err = s.updateOptions()
+	if err != nil {
+		return err
+	}
+
 	// Open the site in the user's browser
 	return s.OpenSite()
 }
@@ -217,10 +329,12 @@ func (s *Site) getLocalAppDir() (string, error) {
 func (s *Site) getRunningConfig(withPlugins bool) (settings.LocalSettings, error) {
 
 	localSettings := settings.LocalSettings{
+		PHP:        s.Settings.PHP,
 		Type:       "site",
 		Local:      false,
 		Xdebug:     false,
 		PhpMyAdmin: false,
+		SSL:        true,
 	}
 
 	// We need container details to see if the phpmyadmin container is running
@@ -230,9 +344,17 @@ func (s *Site) getRunningConfig(withPlugins bool) (settings.LocalSettings, error
 	}
 
 	for _, container := range containers {
-		if container.Image == "phpmyadmin" {
+		if arrayContains(container.Names, fmt.Sprintf("/kana_%s_phpmyadmin", s.Settings.Name)) {
 			localSettings.PhpMyAdmin = true
 		}
+
+		if _, ok := container.Labels["kana.lan"]; ok {
+			localSettings.Lan = true
+		}
+
+		if container.Labels["kana.ssl"] == "false" {
+			localSettings.SSL = false
+		}
 	}
 
 	output, err := s.runCli("pecl list | grep xdebug", false)
@@ -256,6 +378,11 @@ func (s *Site) getRunningConfig(withPlugins bool) (settings.LocalSettings, error
 			localSettings.Local = true
 		}
 
+		// Plugins and themes from local directories are mounted the same way so only the working directory sets the type
+		if mount.Source != s.Settings.WorkingDirectory {
+			continue
+		}
+
 		if strings.Contains(mount.Destination, "/var/www/html/wp-content/plugins/") {
 			localSettings.Type = "plugin"
 		}
@@ -273,22 +400,39 @@ func (s *Site) getRunningConfig(withPlugins bool) (settings.LocalSettings, error
 			return localSettings, err
 		}
 
-		localSettings.Plugins = plugins
+		localSettings.Plugins = getRunningPackages(plugins, s.Settings.Plugins)
+
+		themes, err := s.getInstalledWordPressThemes()
+		if err != nil {
+			return localSettings, err
+		}
+
+		localSettings.Themes = getRunningPackages(themes, s.Settings.Themes)
 	}
 
 	return localSettings, nil
 }
 
 // getSiteURL returns the appropriate URL for the site
-func (s *Site) getSiteURL(insecure bool) string {
+func (s *Site) getSiteURL() string {
 
-	if insecure {
+	if !s.Settings.SSL {
 		return s.Settings.URL
 	}
 
 	return s.Settings.SecureURL
 }
 
+// getURLScheme Returns the scheme the site is served with
+func (s *Site) getURLScheme() string {
+
+	if !s.Settings.SSL {
+		return "http"
+	}
+
+	return "https"
+}
+
 // installXdebug installs xdebug in the site's PHP container
 func (s *Site) installXdebug() (bool, error) {
 
@@ -384,7 +528,10 @@ func (s *Site) runCli(command string, restart bool) (docker.ExecResult, error) {
 func (s *Site) verifySite() (bool, error) {
 
 	// Setup other options generated from config items
-	rootCert := path.Join(s.Settings.AppDirectory, "certs", s.Settings.RootCert)
+	rootCert, err := s.Settings.GetRootCertPath()
+	if err != nil {
+		return false, err
+	}
 
 	caCert, err := os.ReadFile(rootCert)
 	if err != nil {
@@ -399,7 +546,7 @@ func (s *Site) verifySite() (bool, error) {
 		},
 	}
 
-	resp, err := client.Get(s.Settings.SecureURL)
+	resp, err := client.Get(s.getSiteURL())
 	if err != nil {
 		return false, err
 	}
@@ -408,7 +555,7 @@ func (s *Site) verifySite() (bool, error) {
 
 	for resp.StatusCode != 200 {
 
-		resp, err = client.Get(s.Settings.SecureURL)
+		resp, err = client.Get(s.getSiteURL())
 		if err != nil {
 			return false, err
 		}
@@ -1,8 +1,13 @@
 package site
 
 import (
+	"fmt"
+	"os"
 	"path"
+	"regexp"
+	"strings"
 
+	"github.com/ChrisWiegman/kana-cli/pkg/console"
 	"github.com/ChrisWiegman/kana-cli/pkg/docker"
 
 	"github.com/docker/docker/api/types/mount"
@@ -10,6 +15,12 @@ import (
 
 var traefikContainerName = "kana_traefik"
 
+// Where Traefik writes its access log inside its container
+var traefikLogPath = "/var/log/traefik"
+
+// The access log is cleared when Traefik starts once it grows past this size as Traefik never rotates it
+const maxAccessLogSize = 10 * 1024 * 1024
+
 // maybeStopTraefik Checks to see if other sites are running and shuts down the traefik instance if none are
 func (s *Site) maybeStopTraefik() error {
 
@@ -25,24 +36,154 @@ func (s *Site) maybeStopTraefik() error {
 	return nil
 }
 
+// getMiddlewareLabels Translates the site's configured middlewares into Traefik labels, returning them along with the middleware names to attach to its routers
+func (s *Site) getMiddlewareLabels(prefix string) (map[string]string, []string, error) {
+
+	labels := map[string]string{}
+	names := []string{}
+	middlewares := s.Settings.Middlewares
+
+	if middlewares.WWWRedirect {
+
+		name := fmt.Sprintf("%s-www", prefix)
+		names = append(names, name)
+
+		labels[fmt.Sprintf("traefik.http.middlewares.%s.redirectregex.regex", name)] = fmt.Sprintf("^https?://www\\.%s/(.*)", regexp.QuoteMeta(s.Settings.SiteDomain))
+		labels[fmt.Sprintf("traefik.http.middlewares.%s.redirectregex.replacement", name)] = fmt.Sprintf("%s://%s/${1}", s.getURLScheme(), s.Settings.SiteDomain)
+		labels[fmt.Sprintf("traefik.http.middlewares.%s.redirectregex.permanent", name)] = "true"
+	}
+
+	if len(middlewares.BasicAuth) > 0 {
+
+		users, err := middlewares.GetBasicAuthUsers()
+		if err != nil {
+			return labels, names, err
+		}
+
+		name := fmt.Sprintf("%s-basicauth", prefix)
+		names = append(names, name)
+
+		labels[fmt.Sprintf("traefik.http.middlewares.%s.basicauth.users", name)] = strings.Join(users, ",")
+	}
+
+	if len(middlewares.Headers) > 0 {
+
+		name := fmt.Sprintf("%s-headers", prefix)
+		names = append(names, name)
+
+		for header, value := range middlewares.Headers {
+			labels[fmt.Sprintf("traefik.http.middlewares.%s.headers.customresponseheaders.%s", name, header)] = value
+		}
+	}
+
+	if len(middlewares.StripPrefix) > 0 {
+
+		name := fmt.Sprintf("%s-stripprefix", prefix)
+		names = append(names, name)
+
+		labels[fmt.Sprintf("traefik.http.middlewares.%s.stripprefix.prefixes", name)] = strings.Join(middlewares.StripPrefix, ",")
+	}
+
+	return labels, names, nil
+}
+
+// getSiteHostnames Returns the hostnames the site's https routers answer to, including subdomains for multisite installs
+func (s *Site) getSiteHostnames() []string {
+
+	hostnames := []string{
+		s.Settings.SiteDomain,
+		fmt.Sprintf("*.%s", s.Settings.SiteDomain),
+	}
+
+	if s.Settings.PhpMyAdmin {
+		hostnames = append(hostnames, fmt.Sprintf("phpmyadmin-%s", s.Settings.SiteDomain))
+	}
+
+	// The shared *.<app domain> certificate doesn't cover the www host the redirect router answers to
+	if s.Settings.Middlewares.WWWRedirect {
+		hostnames = append(hostnames, fmt.Sprintf("www.%s", s.Settings.SiteDomain))
+	}
+
+	return hostnames
+}
+
+// RegenerateCerts Replaces the Kana certificates and restarts Traefik so running sites use them
+func (s *Site) RegenerateCerts() (string, error) {
+
+	backupPath, err := s.Settings.RegenerateCerts()
+	if err != nil {
+		return backupPath, err
+	}
+
+	_, err = s.dockerClient.ContainerRestart(traefikContainerName)
+
+	return backupPath, err
+}
+
 // startTraefik Starts the Traefik container
 func (s *Site) startTraefik() error {
 
-	err := s.Settings.EnsureSSLCerts()
+	renewed, err := s.Settings.EnsureSSLCerts()
+	if err != nil {
+		return err
+	}
+
+	// Sign a certificate for the LAN domain so devices on the network can load the site over https
+	if s.Settings.Lan {
+
+		lanDomain, err := s.Settings.GetLANDomain()
+		if err != nil {
+			return err
+		}
+
+		lanRenewed, err := s.Settings.EnsureLANCerts(lanDomain)
+		if err != nil {
+			return err
+		}
+
+		renewed = renewed || lanRenewed
+	}
+
+	// Give the site its own certificate so it doesn't depend on the shared wildcard covering all of its hostnames
+	siteRenewed, err := s.Settings.EnsureSiteCerts(s.getSiteHostnames())
 	if err != nil {
 		return err
 	}
 
+	renewed = renewed || siteRenewed
+
+	// Traefik skips reloading dynamic.toml when its contents haven't changed so restart it to load reissued certificates
+	if renewed {
+		_, err = s.dockerClient.ContainerRestart(traefikContainerName)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.MkdirAll(path.Join(s.Settings.AppDirectory, "logs", "traefik"), 0750)
+	if err != nil {
+		return err
+	}
+
+	// Traefik containers started by older versions of Kana don't write the access log. Stopping one removes it so it is recreated below.
+	if s.isTraefikMissingAccessLog() {
+		_, err = s.dockerClient.ContainerStop(traefikContainerName)
+		if err != nil {
+			return err
+		}
+	}
+
 	_, _, err = s.dockerClient.EnsureNetwork("kana")
 	if err != nil {
 		return err
 	}
 
-	err = s.dockerClient.EnsureImage("traefik")
+	err = s.dockerClient.EnsureImage(s.Settings.GetImage("traefik"))
 	if err != nil {
 		return err
 	}
 
+	// Ports are published on all host interfaces so the LAN can reach shared sites
 	traefikPorts := []docker.ExposedPorts{
 		{Port: "80", Protocol: "tcp"},
 		{Port: "443", Protocol: "tcp"},
@@ -51,7 +192,7 @@ func (s *Site) startTraefik() error {
 
 	traefikConfig := docker.ContainerConfig{
 		Name:        traefikContainerName,
-		Image:       "traefik",
+		Image:       s.Settings.GetImage("traefik"),
 		Ports:       traefikPorts,
 		NetworkName: "kana",
 		HostName:    "kanatraefik",
@@ -74,6 +215,11 @@ func (s *Site) startTraefik() error {
 				Source: path.Join(s.Settings.AppDirectory, "certs"),
 				Target: "/var/certs",
 			},
+			{
+				Type:   mount.TypeBind,
+				Source: path.Join(s.Settings.AppDirectory, "logs", "traefik"),
+				Target: traefikLogPath,
+			},
 			{
 				Type:   mount.TypeBind,
 				Source: "/var/run/docker.sock",
@@ -83,8 +229,49 @@ func (s *Site) startTraefik() error {
 	}
 
 	_, err = s.dockerClient.ContainerRun(traefikConfig, false, false)
+	if err != nil {
+		return err
+	}
 
-	return err
+	s.capAccessLog()
+
+	return nil
+}
+
+// isTraefikMissingAccessLog Checks if Traefik is running without the logs folder mounted for its access log
+func (s *Site) isTraefikMissingAccessLog() bool {
+
+	_, isRunning := s.dockerClient.IsContainerRunning(traefikContainerName)
+	if !isRunning {
+		return false
+	}
+
+	for _, mount := range s.dockerClient.ContainerGetMounts(traefikContainerName) {
+		if mount.Destination == traefikLogPath {
+			return false
+		}
+	}
+
+	return true
+}
+
+// capAccessLog Clears the access log once it is larger than maxAccessLogSize. Traefik writes the log as root so it is cleared from inside the container,
+// and as Traefik opens the log for appending it carries on writing from the start of the file. A log that can't be cleared only gets a warning as the site still works.
+func (s *Site) capAccessLog() {
+
+	logInfo, err := os.Stat(path.Join(s.Settings.AppDirectory, "logs", "traefik", "access.log"))
+	if err != nil || logInfo.Size() <= maxAccessLogSize {
+		return
+	}
+
+	output, err := s.dockerClient.ContainerExec(traefikContainerName, []string{fmt.Sprintf("truncate -s 0 %s/access.log", traefikLogPath)})
+	if err == nil && output.ExitCode != 0 {
+		err = fmt.Errorf("truncate exited with code %d %s", output.ExitCode, strings.TrimSpace(output.StdErr))
+	}
+
+	if err != nil {
+		console.Warn(fmt.Sprintf("Unable to clear the Traefik access log: %s.", err))
+	}
 }
 
 // stopTraefik Stops the Traefik container
@@ -0,0 +1,39 @@
+package site
+
+import (
+	"testing"
+
+	"github.com/ChrisWiegman/kana-cli/internal/settings"
+)
+
+func TestWWWRedirectKeepsScheme(t *testing.T) {
+
+	tests := []struct {
+		ssl         bool
+		replacement string
+	}{
+		{true, "https://mysite.sites.kana.li/${1}"},
+		{false, "http://mysite.sites.kana.li/${1}"},
+	}
+
+	for _, test := range tests {
+
+		site := Site{
+			Settings: &settings.Settings{
+				SSL:         test.ssl,
+				SiteDomain:  "mysite.sites.kana.li",
+				Middlewares: settings.Middlewares{WWWRedirect: true},
+			},
+		}
+
+		labels, _, err := site.getMiddlewareLabels("wordpress-mysite")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		replacement := labels["traefik.http.middlewares.wordpress-mysite-www.redirectregex.replacement"]
+		if replacement != test.replacement {
+			t.Errorf("Expected the www redirect with ssl %t to go to %s; got %s", test.ssl, test.replacement, replacement)
+		}
+	}
+}
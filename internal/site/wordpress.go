@@ -3,9 +3,15 @@ package site
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/ChrisWiegman/kana-cli/internal/settings"
 	"github.com/ChrisWiegman/kana-cli/pkg/console"
 	"github.com/ChrisWiegman/kana-cli/pkg/docker"
 	"github.com/logrusorgru/aurora/v4"
@@ -23,6 +29,12 @@ type PluginInfo struct {
 // RunWPCli Runs a wp-cli command returning it's output and any errors
 func (s *Site) RunWPCli(command []string) (int64, string, error) {
 
+	return s.runWPCli(command, "")
+}
+
+// runWPCli Runs a wp-cli command, reading its input from a file in the site's directory when one is given so that secrets can be passed without putting them on the command line
+func (s *Site) runWPCli(command []string, inputFile string) (int64, string, error) {
+
 	var err error
 
 	appDir := path.Join(s.Settings.SiteDirectory, "app")
@@ -46,9 +58,17 @@ func (s *Site) RunWPCli(command []string) (int64, string, error) {
 
 	fullCommand = append(fullCommand, command...)
 
+	if inputFile != "" {
+		fullCommand = []string{
+			"sh",
+			"-c",
+			fmt.Sprintf("%s < %s", quoteShellArgs(fullCommand), quoteShellArgs([]string{path.Join("/Site", inputFile)})),
+		}
+	}
+
 	container := docker.ContainerConfig{
 		Name:        fmt.Sprintf("kana_%s_wordpress_cli", s.Settings.Name),
-		Image:       fmt.Sprintf("wordpress:cli-php%s", s.Settings.PHP),
+		Image:       s.Settings.GetImage("wpcli"),
 		NetworkName: "kana",
 		HostName:    fmt.Sprintf("kana_%s_wordpress_cli", s.Settings.Name),
 		Command:     fullCommand,
@@ -77,8 +97,8 @@ func (s *Site) RunWPCli(command []string) (int64, string, error) {
 	return code, output, nil
 }
 
-// getInstalledWordPressPlugins Returns a list of the plugins that have been installed on the site
-func (s *Site) getInstalledWordPressPlugins() ([]string, error) {
+// getInstalledWordPressPlugins Returns the plugins that have been installed on the site, leaving out drop-ins, the site's own plugin and those that come with WordPress
+func (s *Site) getInstalledWordPressPlugins() ([]PluginInfo, error) {
 
 	commands := []string{
 		"plugin",
@@ -88,21 +108,21 @@ func (s *Site) getInstalledWordPressPlugins() ([]string, error) {
 
 	_, commandOutput, err := s.RunWPCli(commands)
 	if err != nil {
-		return []string{}, err
+		return []PluginInfo{}, err
 	}
 
 	rawPlugins := []PluginInfo{}
-	plugins := []string{}
+	plugins := []PluginInfo{}
 
 	err = json.Unmarshal([]byte(commandOutput), &rawPlugins)
 	if err != nil {
-		return []string{}, err
+		return []PluginInfo{}, err
 	}
 
 	for _, plugin := range rawPlugins {
 
 		if plugin.Status != "dropin" && plugin.Name != s.Settings.Name && plugin.Name != "hello" && plugin.Name != "akismet" {
-			plugins = append(plugins, plugin.Name)
+			plugins = append(plugins, plugin)
 		}
 	}
 
@@ -124,6 +144,55 @@ func (s *Site) getMounts(appDir string) ([]mount.Mount, error) {
 		},
 	}
 
+	// The php.ini file is written every time the containers are created so changes to php_ini are picked up on restart
+	phpIniFile := path.Join(s.Settings.SiteDirectory, "php.ini")
+
+	if err := os.MkdirAll(s.Settings.SiteDirectory, 0750); err != nil {
+		return appVolumes, err
+	}
+
+	if err := os.WriteFile(phpIniFile, []byte(s.Settings.GetPHPIni()), 0644); err != nil {
+		return appVolumes, err
+	}
+
+	appVolumes = append(appVolumes, mount.Mount{ // Loaded after the image's own conf.d files so these directives win
+		Type:     mount.TypeBind,
+		Source:   phpIniFile,
+		Target:   "/usr/local/etc/php/conf.d/zz-kana.ini",
+		ReadOnly: true,
+	})
+
+	for _, packageMounts := range []struct {
+		folder   string
+		packages []settings.Package
+	}{
+		{"plugins", s.Settings.Plugins},
+		{"themes", s.Settings.Themes},
+	} {
+
+		for _, wpPackage := range packageMounts.packages {
+
+			if wpPackage.Type() != settings.PackageDirectory {
+				continue
+			}
+
+			packageDirectory, err := wpPackage.GetPath(s.Settings.WorkingDirectory)
+			if err != nil {
+				return appVolumes, err
+			}
+
+			if _, err = os.Stat(packageDirectory); err != nil {
+				return appVolumes, fmt.Errorf("unable to find the directory for %s: %s", wpPackage.Source, err)
+			}
+
+			appVolumes = append(appVolumes, mount.Mount{ // Maps a local directory from the config as a plugin or theme
+				Type:   mount.TypeBind,
+				Source: packageDirectory,
+				Target: path.Join("/var/www/html", "wp-content", packageMounts.folder, wpPackage.Name()),
+			})
+		}
+	}
+
 	if s.Settings.Type == "plugin" {
 
 		if err := os.MkdirAll(path.Join(s.Settings.WorkingDirectory, "wordpress", "wp-content", "plugins", s.Settings.Name), 0750); err != nil {
@@ -163,7 +232,7 @@ func (s *Site) getWordPressContainers() []string {
 	}
 }
 
-// installDefaultPlugins Installs a list of WordPress plugins
+// installDefaultPlugins Installs and activates the plugins from the site's config
 func (s *Site) installDefaultPlugins() error {
 
 	installedPlugins, err := s.getInstalledWordPressPlugins()
@@ -171,41 +240,397 @@ func (s *Site) installDefaultPlugins() error {
 		return err
 	}
 
-	for _, plugin := range s.Settings.Plugins {
+	return s.installPackages("plugin", s.Settings.Plugins, installedPlugins)
+}
+
+// getInstalledWordPressThemes Returns the themes that have been installed on the site, leaving out the site's own theme and the default themes that come with WordPress unless they are in use
+func (s *Site) getInstalledWordPressThemes() ([]PluginInfo, error) {
+
+	commands := []string{
+		"theme",
+		"list",
+		"--format=json",
+	}
+
+	_, commandOutput, err := s.RunWPCli(commands)
+	if err != nil {
+		return []PluginInfo{}, err
+	}
+
+	rawThemes := []PluginInfo{}
+	themes := []PluginInfo{}
+
+	err = json.Unmarshal([]byte(commandOutput), &rawThemes)
+	if err != nil {
+		return []PluginInfo{}, err
+	}
+
+	configuredThemes := []string{}
+
+	for _, theme := range s.Settings.Themes {
+		configuredThemes = append(configuredThemes, theme.Name())
+	}
+
+	for _, theme := range rawThemes {
+
+		if theme.Name == s.Settings.Name {
+			continue
+		}
+
+		if strings.HasPrefix(theme.Name, "twenty") && theme.Status == "inactive" && !arrayContains(configuredThemes, theme.Name) {
+			continue
+		}
+
+		themes = append(themes, theme)
+	}
+
+	return themes, nil
+}
+
+// installDefaultThemes Installs the themes from the site's config, activating the one marked active
+func (s *Site) installDefaultThemes() error {
+
+	installedThemes, err := s.getInstalledWordPressThemes()
+	if err != nil {
+		return err
+	}
+
+	return s.installPackages("theme", s.Settings.Themes, installedThemes)
+}
+
+// installPackages Installs the plugins or themes from the site's config that aren't installed at the requested version and activates those marked active
+func (s *Site) installPackages(packageType string, packages []settings.Package, installed []PluginInfo) error {
 
-		installPlugin := true
+	for _, wpPackage := range packages {
 
-		for _, installedPlugin := range installedPlugins {
-			if installedPlugin == plugin {
-				installPlugin = false
+		var current *PluginInfo
+
+		for i := range installed {
+			if installed[i].Name == wpPackage.Name() {
+				current = &installed[i]
 			}
 		}
 
-		if installPlugin {
+		isActive := current != nil && strings.HasPrefix(current.Status, "active")
+
+		installCommand, err := s.getPackageInstallCommand(packageType, wpPackage, current)
+		if err != nil {
+			return err
+		}
+
+		if len(installCommand) > 0 {
 
-			console.Println(fmt.Sprintf("Installing plugin:  %s", aurora.Bold(aurora.Blue(plugin))))
+			console.Println(fmt.Sprintf("Installing %s:  %s", packageType, aurora.Bold(aurora.Blue(wpPackage.Source))))
 
-			setupCommand := []string{
-				"plugin",
-				"install",
-				"--activate",
-				plugin,
+			if wpPackage.Active {
+				installCommand = append(installCommand, "--activate")
 			}
 
-			code, _, err := s.RunWPCli(setupCommand)
+			code, _, err := s.RunWPCli(installCommand)
 			if err != nil {
 				return err
 			}
 
 			if code != 0 {
-				console.Warn(fmt.Sprintf("Unable to install plugin: %s.", aurora.Bold(aurora.Blue(plugin))))
+				console.Warn(fmt.Sprintf("Unable to install %s: %s.", packageType, aurora.Bold(aurora.Blue(wpPackage.Source))))
+			}
+
+			continue
+		}
+
+		if !wpPackage.Active || isActive {
+			continue
+		}
+
+		code, _, err := s.RunWPCli([]string{packageType, "activate", wpPackage.Name()})
+		if err != nil {
+			return err
+		}
+
+		if code != 0 {
+			console.Warn(fmt.Sprintf("Unable to activate %s: %s.", packageType, aurora.Bold(aurora.Blue(wpPackage.Name()))))
+		}
+	}
+
+	return nil
+}
+
+// getPackageInstallCommand Returns the wp-cli command to install a plugin or theme, or nothing when it is already installed. Zip files are copied to the site's directory so the container can reach them.
+func (s *Site) getPackageInstallCommand(packageType string, wpPackage settings.Package, current *PluginInfo) ([]string, error) {
+
+	switch wpPackage.Type() {
+	case settings.PackageDirectory:
+		// Directories are mounted into the container with the site
+		return []string{}, nil
+	case settings.PackageWordPressOrg:
+		version := wpPackage.Version()
+
+		if current != nil && (version == "" || current.Version == version) {
+			return []string{}, nil
+		}
+
+		installCommand := []string{packageType, "install", wpPackage.Name()}
+
+		if version != "" {
+			installCommand = append(installCommand, fmt.Sprintf("--version=%s", version))
+		}
+
+		// Replacing an installed copy allows moving to an older version as well as a newer one
+		if current != nil {
+			installCommand = append(installCommand, "--force")
+		}
+
+		return installCommand, nil
+	case settings.PackageZip:
+		if current != nil {
+			return []string{}, nil
+		}
+
+		zipFile, err := wpPackage.GetPath(s.Settings.WorkingDirectory)
+		if err != nil {
+			return []string{}, err
+		}
+
+		packageDirectory := path.Join(s.Settings.SiteDirectory, "packages")
+
+		if err = os.MkdirAll(packageDirectory, 0750); err != nil {
+			return []string{}, err
+		}
+
+		if err = copyFile(zipFile, path.Join(packageDirectory, filepath.Base(zipFile))); err != nil {
+			return []string{}, err
+		}
+
+		return []string{packageType, "install", path.Join("/Site", "packages", filepath.Base(zipFile))}, nil
+	}
+
+	if current != nil {
+		return []string{}, nil
+	}
+
+	return []string{packageType, "install", wpPackage.Source}, nil
+}
+
+// getRunningPackages Returns the plugins or themes installed on a site as they would be written in its config. Zip files, URLs and directories from the config keep their source while everything else is pinned to the installed version.
+func getRunningPackages(installed []PluginInfo, configured []settings.Package) []settings.Package {
+
+	packages := []settings.Package{}
+
+	for _, installedPackage := range installed {
+
+		runningPackage := settings.Package{
+			Source: installedPackage.Name,
+			Active: strings.HasPrefix(installedPackage.Status, "active"),
+		}
+
+		if installedPackage.Version != "" {
+			runningPackage.Source = fmt.Sprintf("%s@%s", installedPackage.Name, installedPackage.Version)
+		}
+
+		// WordPress.org can't install these by name
+		for _, configuredPackage := range configured {
+			if configuredPackage.Name() == installedPackage.Name && configuredPackage.Type() != settings.PackageWordPressOrg {
+				runningPackage.Source = configuredPackage.Source
 			}
 		}
+
+		packages = append(packages, runningPackage)
+	}
+
+	return packages
+}
+
+// updateOptions Sets the WordPress options from the site's config. Values that aren't strings are passed as JSON so that numbers, booleans and arrays keep their type.
+func (s *Site) updateOptions() error {
+
+	for name, value := range s.Settings.Options {
+
+		updateCommand := []string{
+			"option",
+			"update",
+			name,
+		}
+
+		if stringValue, ok := value.(string); ok {
+			updateCommand = append(updateCommand, stringValue)
+		} else {
+
+			jsonValue, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+
+			updateCommand = append(updateCommand, string(jsonValue), "--format=json")
+		}
+
+		code, _, err := s.RunWPCli(updateCommand)
+		if err != nil {
+			return err
+		}
+
+		if code != 0 {
+			console.Warn(fmt.Sprintf("Unable to update option: %s.", aurora.Bold(aurora.Blue(name))))
+		}
 	}
 
 	return nil
 }
 
+// updateConstants Sets the constants from the site's config in wp-config.php and removes any that Kana added before but have since been removed from the config
+func (s *Site) updateConstants() error {
+
+	appliedConstants, err := s.Settings.GetAppliedConstants()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range appliedConstants {
+
+		if _, ok := s.Settings.Constants[name]; ok {
+			continue
+		}
+
+		console.Println(fmt.Sprintf("Removing constant:  %s", aurora.Bold(aurora.Blue(name))))
+
+		// Local sites get a fresh wp-config.php on every start so the constant may already be gone
+		_, _, err = s.RunWPCli([]string{"config", "delete", name, "--type=constant"})
+		if err != nil {
+			return err
+		}
+	}
+
+	names := []string{}
+
+	for name := range s.Settings.Constants {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		setCommand := []string{
+			"config",
+			"set",
+			name,
+		}
+
+		// Strings are quoted in wp-config.php while booleans and numbers are written as raw PHP values
+		switch value := s.Settings.Constants[name].(type) {
+		case string:
+			setCommand = append(setCommand, value)
+		case float64:
+			setCommand = append(setCommand, strconv.FormatFloat(value, 'f', -1, 64), "--raw")
+		default:
+			setCommand = append(setCommand, fmt.Sprint(value), "--raw")
+		}
+
+		setCommand = append(setCommand, "--type=constant")
+
+		code, _, err := s.RunWPCli(setCommand)
+		if err != nil {
+			return err
+		}
+
+		if code != 0 {
+			console.Warn(fmt.Sprintf("Unable to set constant: %s.", aurora.Bold(aurora.Blue(name))))
+		}
+	}
+
+	return s.Settings.SaveAppliedConstants(names)
+}
+
+// ensureWordPressImage Pulls the image for the site's WordPress version, falling back to the catalogue image when there isn't one so that the version can be installed with wp-cli instead
+func (s *Site) ensureWordPressImage() (string, error) {
+
+	image := s.Settings.GetImage("wordpress")
+	versionImage := s.Settings.GetWordPressImage()
+
+	if versionImage == image {
+		return image, s.dockerClient.EnsureImage(image)
+	}
+
+	err := s.dockerClient.EnsureImage(versionImage)
+	if err == nil {
+		return versionImage, nil
+	}
+
+	console.Warn(fmt.Sprintf("There is no %s image so WordPress %s will be installed with wp-cli instead.", aurora.Bold(aurora.Blue(versionImage)), s.Settings.WordPress))
+
+	return image, s.dockerClient.EnsureImage(image)
+}
+
+// ensureWordPressVersion Installs the site's WordPress version with wp-cli when it doesn't match the version that came with the image
+func (s *Site) ensureWordPressVersion() error {
+
+	updateCommand := []string{
+		"core",
+		"update",
+	}
+
+	switch s.Settings.WordPress {
+	case "":
+		return nil
+	case "latest":
+		// wp-cli checks for the latest release itself
+	case "nightly":
+		updateCommand = append(updateCommand, "--version=nightly", "--force")
+	default:
+		currentVersion, err := s.getWordPressVersion()
+		if err != nil {
+			return err
+		}
+
+		if matchesWordPressVersion(currentVersion, s.Settings.WordPress) {
+			return nil
+		}
+
+		// Forcing the update allows the site to be moved to an older version as well as a newer one
+		updateCommand = append(updateCommand, fmt.Sprintf("--version=%s", s.Settings.WordPress), "--force")
+	}
+
+	console.Println(fmt.Sprintf("Installing WordPress: %s", aurora.Bold(aurora.Blue(s.Settings.WordPress))))
+
+	code, output, err := s.RunWPCli(updateCommand)
+	if err != nil {
+		return err
+	}
+
+	if code != 0 {
+		return fmt.Errorf("unable to install WordPress %s: %s", s.Settings.WordPress, strings.TrimSpace(output))
+	}
+
+	// The database may need upgrading, or be newer than the files after moving to an older version
+	_, _, err = s.RunWPCli([]string{"core", "update-db"})
+
+	return err
+}
+
+// matchesWordPressVersion Checks if an installed WordPress version satisfies the version in the config. A version such as 6.1 matches any 6.1.x release, just like the image tags.
+func matchesWordPressVersion(installed, wanted string) bool {
+
+	if installed == wanted {
+		return true
+	}
+
+	return strings.Count(wanted, ".") == 1 && !strings.Contains(wanted, "-") && strings.HasPrefix(installed, fmt.Sprintf("%s.", wanted))
+}
+
+// getWordPressVersion Returns the version of WordPress installed on the site
+func (s *Site) getWordPressVersion() (string, error) {
+
+	code, output, err := s.RunWPCli([]string{"core", "version"})
+	if err != nil {
+		return "", err
+	}
+
+	if code != 0 {
+		return "", fmt.Errorf("unable to get the WordPress version: %s", strings.TrimSpace(output))
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
 // installWordPress Installs and configures WordPress core
 func (s *Site) installWordPress() error {
 
@@ -220,20 +645,85 @@ func (s *Site) installWordPress() error {
 
 		console.Println("Finishing WordPress setup.")
 
+		password := s.Settings.AdminPassword
+
+		if s.Settings.AdminRandomPassword {
+			password, err = settings.GeneratePassword()
+			if err != nil {
+				return err
+			}
+		}
+
+		// The password is read from a file only the current user can read rather than being passed on the command line
+		passwordFile := path.Join(s.Settings.SiteDirectory, ".admin-password")
+
+		err = os.WriteFile(passwordFile, []byte(fmt.Sprintf("%s\n", password)), 0600)
+		if err != nil {
+			return err
+		}
+
+		defer os.Remove(passwordFile)
+
 		setupCommand := []string{
 			"core",
 			"install",
-			fmt.Sprintf("--url=%s", s.getSiteURL(false)),
+			fmt.Sprintf("--url=%s", s.getSiteURL()),
 			fmt.Sprintf("--title=Kana Development %s: %s", s.Settings.Type, s.Settings.Name),
 			fmt.Sprintf("--admin_user=%s", s.Settings.AdminUsername),
-			fmt.Sprintf("--admin_password=%s", s.Settings.AdminPassword),
 			fmt.Sprintf("--admin_email=%s", s.Settings.AdminEmail),
+			"--prompt=admin_password",
 		}
 
-		code, _, err = s.RunWPCli(setupCommand)
-		if err != nil || code != 0 {
+		code, output, err := s.runWPCli(setupCommand, filepath.Base(passwordFile))
+		if err != nil {
 			return fmt.Errorf("installation of WordPress failed: %s", err.Error())
 		}
+
+		if code != 0 {
+			return fmt.Errorf("installation of WordPress failed: %s", strings.TrimSpace(output))
+		}
+
+		// Generated passwords aren't saved anywhere so this is the only chance to see them
+		if s.Settings.AdminRandomPassword {
+			console.Println(fmt.Sprintf("The password for %s is %s. Kana doesn't save it so please store it somewhere safe.", aurora.Bold(aurora.Blue(s.Settings.AdminUsername)), aurora.Bold(aurora.Blue(password))))
+		}
+
+		return nil
+	}
+
+	return s.ensureSiteURL()
+}
+
+// ensureSiteURL Updates the WordPress home and site URLs when the site's ssl setting has changed since it was installed
+func (s *Site) ensureSiteURL() error {
+
+	code, output, err := s.RunWPCli([]string{"option", "get", "siteurl"})
+	if err != nil || code != 0 {
+		return err
+	}
+
+	siteURL := strings.TrimSuffix(s.Settings.SecureURL, "/")
+	oldURL := strings.TrimSuffix(s.Settings.URL, "/")
+
+	if !s.Settings.SSL {
+		siteURL, oldURL = oldURL, siteURL
+	}
+
+	// Only switch the scheme. Leave any other URL the user has set alone.
+	if strings.TrimSpace(output) != oldURL {
+		return nil
+	}
+
+	for _, option := range []string{"home", "siteurl"} {
+
+		code, output, err = s.RunWPCli([]string{"option", "update", option, siteURL})
+		if err != nil {
+			return err
+		}
+
+		if code != 0 {
+			return fmt.Errorf("unable to update the site URL: %s", output)
+		}
 	}
 
 	return nil
@@ -277,10 +767,69 @@ func (s *Site) startWordPress() error {
 		return err
 	}
 
+	wordPressImage, err := s.ensureWordPressImage()
+	if err != nil {
+		return err
+	}
+
+	hostRule := fmt.Sprintf("Host(`%s`)", s.Settings.SiteDomain)
+	wordPressEnv := []string{
+		fmt.Sprintf("WORDPRESS_DB_HOST=kana_%s_database", s.Settings.Name),
+		"WORDPRESS_DB_USER=wordpress",
+		"WORDPRESS_DB_PASSWORD=wordpress",
+		"WORDPRESS_DB_NAME=wordpress",
+	}
+	wordPressLabels := map[string]string{
+		"kana.site": s.Settings.Name,
+	}
+
+	if s.Settings.Lan {
+
+		lanSiteDomain, err := s.getLANSiteDomain()
+		if err != nil {
+			return err
+		}
+
+		hostRule = fmt.Sprintf("%s || Host(`%s`)", hostRule, lanSiteDomain)
+		wordPressEnv = append(wordPressEnv, fmt.Sprintf("WORDPRESS_CONFIG_EXTRA=%s", getLANConfigExtra(lanSiteDomain, s.getURLScheme())))
+		wordPressLabels["kana.lan"] = lanSiteDomain
+	}
+
+	if s.Settings.Middlewares.WWWRedirect {
+		hostRule = fmt.Sprintf("%s || Host(`www.%s`)", hostRule, s.Settings.SiteDomain)
+	}
+
+	middlewareLabels, middlewares, err := s.getMiddlewareLabels(fmt.Sprintf("wordpress-%s", s.Settings.Name))
+	if err != nil {
+		return err
+	}
+
+	for label, value := range middlewareLabels {
+		wordPressLabels[label] = value
+	}
+
+	// Serve the site over plain HTTP by outranking the global https redirect router Traefik creates on the web entrypoint
+	if !s.Settings.SSL {
+		wordPressLabels[fmt.Sprintf("traefik.http.routers.wordpress-%s-http.priority", s.Settings.Name)] = strconv.Itoa(math.MaxInt32)
+		wordPressLabels["kana.ssl"] = "false"
+	}
+
+	if len(middlewares) > 0 {
+		wordPressLabels[fmt.Sprintf("traefik.http.routers.wordpress-%s-http.middlewares", s.Settings.Name)] = strings.Join(middlewares, ",")
+		wordPressLabels[fmt.Sprintf("traefik.http.routers.wordpress-%s.middlewares", s.Settings.Name)] = strings.Join(middlewares, ",")
+	}
+
+	wordPressLabels["traefik.enable"] = "true"
+	wordPressLabels[fmt.Sprintf("traefik.http.routers.wordpress-%s-http.entrypoints", s.Settings.Name)] = "web"
+	wordPressLabels[fmt.Sprintf("traefik.http.routers.wordpress-%s-http.rule", s.Settings.Name)] = hostRule
+	wordPressLabels[fmt.Sprintf("traefik.http.routers.wordpress-%s.entrypoints", s.Settings.Name)] = "websecure"
+	wordPressLabels[fmt.Sprintf("traefik.http.routers.wordpress-%s.rule", s.Settings.Name)] = hostRule
+	wordPressLabels[fmt.Sprintf("traefik.http.routers.wordpress-%s.tls", s.Settings.Name)] = "true"
+
 	wordPressContainers := []docker.ContainerConfig{
 		{
 			Name:        fmt.Sprintf("kana_%s_database", s.Settings.Name),
-			Image:       "mariadb",
+			Image:       s.Settings.GetImage("mariadb"),
 			NetworkName: "kana",
 			HostName:    fmt.Sprintf("kana_%s_database", s.Settings.Name),
 			Ports: []docker.ExposedPorts{
@@ -305,25 +854,12 @@ func (s *Site) startWordPress() error {
 		},
 		{
 			Name:        fmt.Sprintf("kana_%s_wordpress", s.Settings.Name),
-			Image:       fmt.Sprintf("wordpress:php%s", s.Settings.PHP),
+			Image:       wordPressImage,
 			NetworkName: "kana",
 			HostName:    fmt.Sprintf("kana_%s_wordpress", s.Settings.Name),
-			Env: []string{
-				fmt.Sprintf("WORDPRESS_DB_HOST=kana_%s_database", s.Settings.Name),
-				"WORDPRESS_DB_USER=wordpress",
-				"WORDPRESS_DB_PASSWORD=wordpress",
-				"WORDPRESS_DB_NAME=wordpress",
-			},
-			Labels: map[string]string{
-				"traefik.enable": "true",
-				fmt.Sprintf("traefik.http.routers.wordpress-%s-http.entrypoints", s.Settings.Name): "web",
-				fmt.Sprintf("traefik.http.routers.wordpress-%s-http.rule", s.Settings.Name):        fmt.Sprintf("Host(`%s`)", s.Settings.SiteDomain),
-				fmt.Sprintf("traefik.http.routers.wordpress-%s.entrypoints", s.Settings.Name):      "websecure",
-				fmt.Sprintf("traefik.http.routers.wordpress-%s.rule", s.Settings.Name):             fmt.Sprintf("Host(`%s`)", s.Settings.SiteDomain),
-				fmt.Sprintf("traefik.http.routers.wordpress-%s.tls", s.Settings.Name):              "true",
-				"kana.site": s.Settings.Name,
-			},
-			Volumes: appVolumes,
+			Env:         wordPressEnv,
+			Labels:      wordPressLabels,
+			Volumes:     appVolumes,
 		},
 	}
 
@@ -331,7 +867,7 @@ func (s *Site) startWordPress() error {
 
 		phpMyAdminContainer := docker.ContainerConfig{
 			Name:        fmt.Sprintf("kana_%s_phpmyadmin", s.Settings.Name),
-			Image:       "phpmyadmin",
+			Image:       s.Settings.GetImage("phpmyadmin"),
 			NetworkName: "kana",
 			HostName:    fmt.Sprintf("kana_%s_phpmyadmin", s.Settings.Name),
 			Env: []string{
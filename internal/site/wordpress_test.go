@@ -0,0 +1,36 @@
+package site
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ChrisWiegman/kana-cli/internal/settings"
+)
+
+func TestGetRunningPackages(t *testing.T) {
+
+	installed := []PluginInfo{
+		{Name: "akismet", Status: "active", Version: "5.0.2"},
+		{Name: "query-monitor", Status: "inactive", Version: "3.11.1"},
+		{Name: "my-plugin", Status: "active", Version: "1.0.0"},
+		{Name: "hello", Status: "inactive"},
+	}
+
+	configured := []settings.Package{
+		{Source: "akismet", Active: true},
+		{Source: "query-monitor@3.10.0", Active: true},
+		{Source: "./plugins/my-plugin.zip", Active: true},
+	}
+
+	expected := []settings.Package{
+		{Source: "akismet@5.0.2", Active: true},
+		{Source: "query-monitor@3.11.1", Active: false},
+		{Source: "./plugins/my-plugin.zip", Active: true},
+		{Source: "hello", Active: false},
+	}
+
+	packages := getRunningPackages(installed, configured)
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected %v; got %v", expected, packages)
+	}
+}
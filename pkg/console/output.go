@@ -9,6 +9,9 @@ import (
 	"github.com/logrusorgru/aurora/v4"
 )
 
+// stdin is shared by all prompts so that input buffered while reading one answer isn't lost before the next
+var stdin = bufio.NewReader(os.Stdin)
+
 // Bold outputs the requested text as bold
 func Bold(output string) string {
 	return fmt.Sprint(aurora.Bold(output))
@@ -40,12 +43,11 @@ func PromptConfirm(promptText string, def bool) bool {
 		choices = "y/N"
 	}
 
-	r := bufio.NewReader(os.Stdin)
 	var s string
 
 	for {
 		fmt.Fprintf(os.Stderr, "%s (%s) ", promptText, choices)
-		s, _ = r.ReadString('\n')
+		s, _ = stdin.ReadString('\n')
 		s = strings.TrimSpace(s)
 		if s == "" {
 			return def
@@ -60,6 +62,25 @@ func PromptConfirm(promptText string, def bool) bool {
 	}
 }
 
+// PromptString asks the user for a value, returning the default if they don't enter one.
+func PromptString(promptText, def string) string {
+
+	if def != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s] ", promptText, def)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s ", promptText)
+	}
+
+	s, _ := stdin.ReadString('\n')
+	s = strings.TrimSpace(s)
+
+	if s == "" {
+		return def
+	}
+
+	return s
+}
+
 // Success displays a formatted success message on successful completion of the command
 func Success(output string) {
 	fmt.Printf("%s %s\n", aurora.Bold(aurora.Green("[Success]")), output)
@@ -31,19 +31,11 @@ func (d *DockerClient) EnsureImage(imageName string) (err error) {
 		imageName = fmt.Sprintf("%s:latest", imageName)
 	}
 
-	images, err := d.client.ImageList(context.Background(), types.ImageListOptions{})
-	if err != nil {
+	exists, err := d.ImageExists(imageName)
+	if err != nil || exists {
 		return err
 	}
 
-	for _, image := range images {
-		for _, imageTag := range image.RepoTags {
-			if imageTag == imageName {
-				return nil
-			}
-		}
-	}
-
 	events, err := d.client.ImagePull(context.Background(), imageName, types.ImagePullOptions{})
 	if err != nil {
 		return err
@@ -127,6 +119,29 @@ func (d *DockerClient) EnsureImage(imageName string) (err error) {
 	return nil
 }
 
+// ImageExists Checks if the given image has already been pulled
+func (d *DockerClient) ImageExists(imageName string) (bool, error) {
+
+	if !strings.Contains(imageName, ":") {
+		imageName = fmt.Sprintf("%s:latest", imageName)
+	}
+
+	images, err := d.client.ImageList(context.Background(), types.ImageListOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	for _, image := range images {
+		for _, imageTag := range image.RepoTags {
+			if imageTag == imageName {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
 func (d *DockerClient) RemoveImage(image string) (removed bool, err error) {
 
 	removedResponse, err := d.client.ImageRemove(context.Background(), image, types.ImageRemoveOptions{})
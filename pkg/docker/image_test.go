@@ -53,3 +53,41 @@ func TestRemoveImage(t *testing.T) {
 		t.Errorf("Image should not have been removed but was")
 	}
 }
+
+func TestImageExists(t *testing.T) {
+
+	d, err := NewController()
+
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	err = d.EnsureImage("alpine")
+	if err != nil {
+		t.Error(err)
+	}
+
+	exists, err := d.ImageExists("alpine")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if exists != true {
+		t.Errorf("Image should exist but doesn't")
+	}
+
+	_, err = d.RemoveImage("alpine")
+	if err != nil {
+		t.Error(err)
+	}
+
+	exists, err = d.ImageExists("alpine")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if exists == true {
+		t.Errorf("Image should not exist but does")
+	}
+}
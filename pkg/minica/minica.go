@@ -3,24 +3,39 @@ package minica
 import (
 	"bytes"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io/fs"
 	"math"
 	"math/big"
-	"os"
-	"path"
 	"time"
 )
 
-type issuer struct {
-	key  crypto.Signer
-	cert *x509.Certificate
+// The key algorithms certificates can be issued with
+const (
+	KeyTypeECDSA = "ecdsa"
+	KeyTypeRSA   = "rsa"
+)
+
+// Certificate A certificate and its private key
+type Certificate struct {
+	Key  crypto.Signer
+	Cert *x509.Certificate
+}
+
+// Authority A CA certificate and key that can issue certificates
+type Authority struct {
+	Certificate
 }
 
 type CertInfo struct {
@@ -30,57 +45,72 @@ type CertInfo struct {
 	RootCert   string
 	SiteCert   string
 	SiteKey    string
+	// Domains are the names the certificate is issued for. A wildcard for CertDomain is used when empty.
+	Domains []string
+	// KeyType is the algorithm new keys are generated with. ECDSA P-256 is used when empty.
+	KeyType string
+	// PermittedDomains limits the names a newly created root can sign certificates for. The root is unconstrained when empty.
+	PermittedDomains []string
+	// ExternalCA signs with an existing CA. RootKey and RootCert are full paths and are never created or modified.
+	ExternalCA bool
 }
 
+// GenCerts Signs a certificate for the domains in certInfo, creating the root CA first if needed, and writes it to CertDir
 func GenCerts(certInfo CertInfo) error {
 
-	caKey := path.Join(certInfo.CertDir, certInfo.RootKey)
-	caCert := path.Join(certInfo.CertDir, certInfo.RootCert)
-	domains := []string{
-		fmt.Sprintf("*.%s", certInfo.CertDomain),
+	caStorage := FileStorage{Dir: certInfo.CertDir}
+	domains := certInfo.Domains
+
+	if len(domains) == 0 {
+		domains = []string{
+			fmt.Sprintf("*.%s", certInfo.CertDomain),
+		}
+	}
+
+	if certInfo.ExternalCA {
+		caStorage = FileStorage{}
 	}
 
-	issuer, err := getIssuer(caKey, caCert)
+	authority, err := getAuthority(caStorage, certInfo.RootKey, certInfo.RootCert, !certInfo.ExternalCA, certInfo.KeyType, certInfo.PermittedDomains)
 	if err != nil {
 		return err
 	}
 
-	_, err = sign(issuer, domains, certInfo.CertDir, certInfo.SiteCert, certInfo.SiteKey)
+	cert, err := authority.Issue(domains, certInfo.KeyType)
+	if err != nil {
+		return err
+	}
 
-	return err
+	return cert.Save(FileStorage{Dir: certInfo.CertDir}, certInfo.SiteCert, certInfo.SiteKey)
 }
 
-func getIssuer(keyFile, certFile string) (*issuer, error) {
-
-	keyContents, keyErr := os.ReadFile(keyFile)
-	certContents, certErr := os.ReadFile(certFile)
-
-	if os.IsNotExist(keyErr) && os.IsNotExist(certErr) {
-
-		err := makeIssuer(keyFile, certFile)
-		if err != nil {
-			return nil, err
-		}
-
-		return getIssuer(keyFile, certFile)
+// NewAuthority Creates a new root CA in memory, optionally limited to signing certificates for permittedDomains
+func NewAuthority(keyType string, permittedDomains []string) (*Authority, error) {
 
-	} else if keyErr != nil {
+	key, err := makeKey(keyType)
+	if err != nil {
+		return nil, err
+	}
 
-		return nil, fmt.Errorf("%s (but %s exists)", keyErr, certFile)
+	cert, err := makeRootCert(key, permittedDomains)
+	if err != nil {
+		return nil, err
+	}
 
-	} else if certErr != nil {
+	return &Authority{Certificate{Key: key, Cert: cert}}, nil
+}
 
-		return nil, fmt.Errorf("%s (but %s exists)", certErr, keyFile)
-	}
+// ParseAuthority Parses a PEM encoded CA key and certificate, making sure they belong together
+func ParseAuthority(keyContents, certContents []byte) (*Authority, error) {
 
 	key, err := readPrivateKey(keyContents)
 	if err != nil {
-		return nil, fmt.Errorf("reading private key from %s: %s", keyFile, err)
+		return nil, fmt.Errorf("reading private key: %s", err)
 	}
 
 	cert, err := readCert(certContents)
 	if err != nil {
-		return nil, fmt.Errorf("reading CA certificate from %s: %s", certFile, err)
+		return nil, fmt.Errorf("reading CA certificate: %s", err)
 	}
 
 	equal, err := publicKeysEqual(key.Public(), cert.PublicKey)
@@ -90,92 +120,257 @@ func getIssuer(keyFile, certFile string) (*issuer, error) {
 
 	} else if !equal {
 
-		return nil, fmt.Errorf("public key in CA certificate %s doesn't match private key in %s",
-			certFile, keyFile)
+		return nil, fmt.Errorf("public key in CA certificate doesn't match private key")
 	}
 
-	return &issuer{key, cert}, nil
+	return &Authority{Certificate{Key: key, Cert: cert}}, nil
 }
 
-func readPrivateKey(keyContents []byte) (crypto.Signer, error) {
+// LoadAuthority Reads a CA key and certificate from storage
+func LoadAuthority(storage Storage, keyName, certName string) (*Authority, error) {
 
-	block, _ := pem.Decode(keyContents)
-	if block == nil {
+	keyContents, err := storage.Read(keyName)
+	if err != nil {
+		return nil, err
+	}
 
-		return nil, fmt.Errorf("no PEM found")
+	certContents, err := storage.Read(certName)
+	if err != nil {
+		return nil, err
+	}
 
-	} else if block.Type != "RSA PRIVATE KEY" && block.Type != "ECDSA PRIVATE KEY" {
+	return parseStoredAuthority(keyContents, certContents, keyName, certName)
+}
 
-		return nil, fmt.Errorf("incorrect PEM type %s", block.Type)
+// Issue Signs a new certificate for the domains. The first domain is used as the common name.
+func (a *Authority) Issue(domains []string, keyType string) (*Certificate, error) {
 
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("at least one domain is required")
 	}
 
-	return x509.ParsePKCS1PrivateKey(block.Bytes)
-}
+	key, err := makeKey(keyType)
+	if err != nil {
+		return nil, err
+	}
 
-func readCert(certContents []byte) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+	if err != nil {
+		return nil, err
+	}
 
-	block, _ := pem.Decode(certContents)
-	if block == nil {
+	// Key encipherment only applies to RSA key exchange
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
 
-		return nil, fmt.Errorf("no PEM found")
+	template := &x509.Certificate{
+		DNSNames: domains,
+		Subject: pkix.Name{
+			CommonName: domains[0],
+		},
+		SerialNumber: serial,
+		NotBefore:    time.Now(),
+		// Set the validity period to 2 years and 30 days, to satisfy the iOS and
+		// macOS requirements that all server certificates must have validity
+		// shorter than 825 days:
+		// https://derflounder.wordpress.com/2019/06/06/new-tls-security-requirements-for-ios-13-and-macos-catalina-10-15/
+		NotAfter: time.Now().AddDate(2, 0, 30),
 
-	} else if block.Type != "CERTIFICATE" {
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  false,
+	}
 
-		return nil, fmt.Errorf("incorrect PEM type %s", block.Type)
+	der, err := x509.CreateCertificate(rand.Reader, template, a.Cert, key.Public(), a.Key)
+	if err != nil {
+		return nil, err
+	}
 
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
 	}
 
-	return x509.ParseCertificate(block.Bytes)
+	return &Certificate{Key: key, Cert: cert}, nil
 }
 
-func makeIssuer(keyFile, certFile string) error {
+// CertPEM Returns the PEM encoded certificate
+func (c *Certificate) CertPEM() []byte {
 
-	key, err := makeKey(keyFile)
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: c.Cert.Raw,
+	})
+}
+
+// KeyPEM Returns the PEM encoded private key
+func (c *Certificate) KeyPEM() ([]byte, error) {
+
+	block, err := marshalPrivateKey(c.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(block), nil
+}
+
+// TLSCertificate Returns the certificate and key ready to be served by crypto/tls
+func (c *Certificate) TLSCertificate() tls.Certificate {
+
+	return tls.Certificate{
+		Certificate: [][]byte{c.Cert.Raw},
+		PrivateKey:  c.Key,
+		Leaf:        c.Cert,
+	}
+}
+
+// Save Writes the certificate and key to storage without replacing anything already there
+func (c *Certificate) Save(storage Storage, certName, keyName string) error {
+
+	keyContents, err := c.KeyPEM()
 	if err != nil {
 		return err
 	}
 
-	_, err = makeRootCert(key, certFile)
+	err = storage.Write(keyName, keyContents)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return storage.Write(certName, c.CertPEM())
 }
 
-func makeKey(filename string) (*rsa.PrivateKey, error) {
+// getAuthority Loads the CA from storage, optionally creating and saving a new one if neither file exists yet
+func getAuthority(storage Storage, keyName, certName string, create bool, keyType string, permittedDomains []string) (*Authority, error) {
 
-	key, err := rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
-		return nil, err
+	keyContents, keyErr := storage.Read(keyName)
+	certContents, certErr := storage.Read(certName)
+
+	if errors.Is(keyErr, fs.ErrNotExist) && errors.Is(certErr, fs.ErrNotExist) && create {
+
+		authority, err := NewAuthority(keyType, permittedDomains)
+		if err != nil {
+			return nil, err
+		}
+
+		return authority, authority.Save(storage, certName, keyName)
+
+	} else if keyErr != nil {
+
+		return nil, fmt.Errorf("%s (but %s exists)", keyErr, certName)
+
+	} else if certErr != nil {
+
+		return nil, fmt.Errorf("%s (but %s exists)", certErr, keyName)
 	}
 
-	der := x509.MarshalPKCS1PrivateKey(key)
+	return parseStoredAuthority(keyContents, certContents, keyName, certName)
+}
+
+// parseStoredAuthority Parses a CA read from storage, naming the files involved in any errors
+func parseStoredAuthority(keyContents, certContents []byte, keyName, certName string) (*Authority, error) {
+
+	authority, err := ParseAuthority(keyContents, certContents)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%s and %s: %s", keyName, certName, err)
 	}
 
-	file, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
-	if err != nil {
-		return nil, err
+	return authority, nil
+}
+
+func readPrivateKey(keyContents []byte) (crypto.Signer, error) {
+
+	block, _ := pem.Decode(keyContents)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM found")
 	}
 
-	defer file.Close()
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY", "ECDSA PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		// PKCS #8 keys, as created by mkcert and most other tools
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
 
-	err = pem.Encode(file, &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: der,
-	})
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+
+		return signer, nil
+	}
+
+	return nil, fmt.Errorf("incorrect PEM type %s", block.Type)
+}
+
+func readCert(certContents []byte) (*x509.Certificate, error) {
+
+	block, _ := pem.Decode(certContents)
+	if block == nil {
+
+		return nil, fmt.Errorf("no PEM found")
+
+	} else if block.Type != "CERTIFICATE" {
 
+		return nil, fmt.Errorf("incorrect PEM type %s", block.Type)
+
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func makeKey(keyType string) (crypto.Signer, error) {
+
+	switch keyType {
+	case KeyTypeRSA:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case KeyTypeECDSA, "":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+
+	return nil, fmt.Errorf("unsupported key type %s", keyType)
+}
+
+func marshalPrivateKey(key crypto.Signer) (*pem.Block, error) {
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(k),
+		}, nil
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+
+		return &pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: der,
+		}, nil
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
 	if err != nil {
 		return nil, err
 	}
 
-	return key, nil
+	return &pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: der,
+	}, nil
 }
 
-func makeRootCert(key crypto.Signer, filename string) (*x509.Certificate, error) {
+func makeRootCert(key crypto.Signer, permittedDomains []string) (*x509.Certificate, error) {
 
 	serial, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
 
@@ -208,24 +403,13 @@ func makeRootCert(key crypto.Signer, filename string) (*x509.Certificate, error)
 		BasicConstraintsValid: true,
 		IsCA:                  true,
 		MaxPathLenZero:        true,
-	}
 
-	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
-	if err != nil {
-		return nil, err
+		// Limit the damage a leaked key could do to the domains Kana serves
+		PermittedDNSDomains:         permittedDomains,
+		PermittedDNSDomainsCritical: len(permittedDomains) > 0,
 	}
 
-	file, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
-	if err != nil {
-		return nil, err
-	}
-
-	defer file.Close()
-
-	err = pem.Encode(file, &pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: der,
-	})
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
 	if err != nil {
 		return nil, err
 	}
@@ -268,60 +452,3 @@ func calculateSKID(pubKey crypto.PublicKey) ([]byte, error) {
 	skid := sha1.Sum(spki.SubjectPublicKey.Bytes)
 	return skid[:], nil
 }
-
-func sign(iss *issuer, domains []string, certPath, siteCert, siteKey string) (*x509.Certificate, error) {
-
-	cn := domains[0]
-
-	key, err := makeKey(path.Join(certPath, siteKey))
-	if err != nil {
-		return nil, err
-	}
-
-	serial, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
-	if err != nil {
-		return nil, err
-	}
-
-	template := &x509.Certificate{
-		DNSNames: domains,
-		Subject: pkix.Name{
-			CommonName: cn,
-		},
-		SerialNumber: serial,
-		NotBefore:    time.Now(),
-		// Set the validity period to 2 years and 30 days, to satisfy the iOS and
-		// macOS requirements that all server certificates must have validity
-		// shorter than 825 days:
-		// https://derflounder.wordpress.com/2019/06/06/new-tls-security-requirements-for-ios-13-and-macos-catalina-10-15/
-		NotAfter: time.Now().AddDate(2, 0, 30),
-
-		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
-		BasicConstraintsValid: true,
-		IsCA:                  false,
-	}
-
-	der, err := x509.CreateCertificate(rand.Reader, template, iss.cert, key.Public(), iss.key)
-	if err != nil {
-		return nil, err
-	}
-
-	file, err := os.OpenFile(path.Join(certPath, siteCert), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
-	if err != nil {
-		return nil, err
-	}
-
-	defer file.Close()
-
-	err = pem.Encode(file, &pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: der,
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	return x509.ParseCertificate(der)
-}
@@ -0,0 +1,421 @@
+package minica
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestAuthority(t *testing.T, keyType string, permittedDomains []string) *Authority {
+
+	authority, err := NewAuthority(keyType, permittedDomains)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return authority
+}
+
+func getRoots(authority *Authority) *x509.CertPool {
+
+	roots := x509.NewCertPool()
+	roots.AddCert(authority.Cert)
+
+	return roots
+}
+
+func TestNewAuthority(t *testing.T) {
+
+	authority := newTestAuthority(t, "", []string{"sites.kana.li"})
+	root := authority.Cert
+
+	if !root.IsCA || !root.BasicConstraintsValid || !root.MaxPathLenZero {
+		t.Error("Root should be a CA that can't issue intermediates")
+	}
+
+	if root.KeyUsage&x509.KeyUsageCertSign == 0 {
+		t.Error("Root should be able to sign certificates")
+	}
+
+	err := root.CheckSignatureFrom(root)
+	if err != nil {
+		t.Errorf("Root should be self-signed: %s", err)
+	}
+
+	key, ok := authority.Key.(*ecdsa.PrivateKey)
+	if !ok || key.Curve != elliptic.P256() {
+		t.Errorf("Root key should default to ECDSA P-256 but is %T", authority.Key)
+	}
+
+	if !reflect.DeepEqual(root.PermittedDNSDomains, []string{"sites.kana.li"}) || !root.PermittedDNSDomainsCritical {
+		t.Errorf("Root should be limited to sites.kana.li but permits %v", root.PermittedDNSDomains)
+	}
+
+	if root.NotAfter.Before(time.Now().AddDate(99, 0, 0)) {
+		t.Errorf("Root should be valid for 100 years but expires %s", root.NotAfter)
+	}
+}
+
+func TestNewAuthorityRSA(t *testing.T) {
+
+	authority := newTestAuthority(t, KeyTypeRSA, nil)
+
+	key, ok := authority.Key.(*rsa.PrivateKey)
+	if !ok || key.N.BitLen() != 2048 {
+		t.Errorf("Root key should be RSA 2048 but is %T", authority.Key)
+	}
+
+	if len(authority.Cert.PermittedDNSDomains) != 0 || authority.Cert.PermittedDNSDomainsCritical {
+		t.Error("Root should be unconstrained when no domains are permitted")
+	}
+
+	_, err := NewAuthority("dsa", nil)
+	if err == nil {
+		t.Error("Unsupported key types should be rejected")
+	}
+}
+
+func TestIssue(t *testing.T) {
+
+	authority := newTestAuthority(t, KeyTypeECDSA, []string{"sites.kana.li"})
+	domains := []string{"mysite.sites.kana.li", "*.mysite.sites.kana.li"}
+
+	cert, err := authority.Issue(domains, KeyTypeECDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf := cert.Cert
+
+	if !reflect.DeepEqual(leaf.DNSNames, domains) {
+		t.Errorf("Expected SANs %v but got %v", domains, leaf.DNSNames)
+	}
+
+	if leaf.Subject.CommonName != domains[0] {
+		t.Errorf("Expected common name %s but got %s", domains[0], leaf.Subject.CommonName)
+	}
+
+	if leaf.IsCA {
+		t.Error("Leaf certificates should not be CAs")
+	}
+
+	if leaf.KeyUsage != x509.KeyUsageDigitalSignature {
+		t.Errorf("ECDSA leaf certificates should only allow digital signatures but have key usage %d", leaf.KeyUsage)
+	}
+
+	if !reflect.DeepEqual(leaf.ExtKeyUsage, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}) {
+		t.Errorf("Unexpected extended key usage %v", leaf.ExtKeyUsage)
+	}
+
+	// Apple platforms reject server certificates valid for 825 days or more
+	if leaf.NotAfter.Sub(leaf.NotBefore) >= 825*24*time.Hour {
+		t.Errorf("Leaf certificates should be valid for less than 825 days but expire %s", leaf.NotAfter)
+	}
+
+	for _, name := range []string{"mysite.sites.kana.li", "www.mysite.sites.kana.li"} {
+
+		_, err = leaf.Verify(x509.VerifyOptions{
+			DNSName: name,
+			Roots:   getRoots(authority),
+		})
+		if err != nil {
+			t.Errorf("Leaf should verify for %s: %s", name, err)
+		}
+	}
+
+	_, err = leaf.Verify(x509.VerifyOptions{
+		DNSName: "othersite.sites.kana.li",
+		Roots:   getRoots(authority),
+	})
+	if err == nil {
+		t.Error("Leaf should not verify for names it wasn't issued for")
+	}
+}
+
+func TestIssueRSA(t *testing.T) {
+
+	authority := newTestAuthority(t, KeyTypeECDSA, nil)
+
+	cert, err := authority.Issue([]string{"mysite.sites.kana.li"}, KeyTypeRSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cert.Cert.KeyUsage != x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment {
+		t.Errorf("RSA leaf certificates should allow key encipherment but have key usage %d", cert.Cert.KeyUsage)
+	}
+
+	_, err = authority.Issue([]string{}, KeyTypeRSA)
+	if err == nil {
+		t.Error("Issuing a certificate without any domains should fail")
+	}
+}
+
+func TestIssueExpiry(t *testing.T) {
+
+	authority := newTestAuthority(t, KeyTypeECDSA, nil)
+
+	cert, err := authority.Issue([]string{"mysite.sites.kana.li"}, KeyTypeECDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cert.Cert.Verify(x509.VerifyOptions{
+		DNSName:     "mysite.sites.kana.li",
+		Roots:       getRoots(authority),
+		CurrentTime: cert.Cert.NotAfter.Add(time.Hour),
+	})
+	if err == nil {
+		t.Error("Leaf should not verify after it has expired")
+	}
+}
+
+func TestIssueOutsideNameConstraints(t *testing.T) {
+
+	authority := newTestAuthority(t, KeyTypeECDSA, []string{"sites.kana.li"})
+
+	cert, err := authority.Issue([]string{"www.example.com"}, KeyTypeECDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cert.Cert.Verify(x509.VerifyOptions{
+		DNSName: "www.example.com",
+		Roots:   getRoots(authority),
+	})
+	if err == nil {
+		t.Error("Leaf should not verify for names outside the root's permitted domains")
+	}
+}
+
+func TestTLSCertificate(t *testing.T) {
+
+	authority := newTestAuthority(t, KeyTypeECDSA, []string{"sites.kana.li"})
+
+	cert, err := authority.Issue([]string{"mysite.sites.kana.li"}, KeyTypeECDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverConn, clientConn := net.Pipe()
+
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	server := tls.Server(serverConn, &tls.Config{
+		Certificates: []tls.Certificate{cert.TLSCertificate()},
+	})
+
+	go server.Handshake()
+
+	client := tls.Client(clientConn, &tls.Config{
+		RootCAs:    getRoots(authority),
+		ServerName: "mysite.sites.kana.li",
+	})
+
+	err = client.Handshake()
+	if err != nil {
+		t.Errorf("Handshake with the issued certificate failed: %s", err)
+	}
+}
+
+func TestParseAuthority(t *testing.T) {
+
+	authority := newTestAuthority(t, KeyTypeECDSA, nil)
+
+	keyContents, err := authority.KeyPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseAuthority(keyContents, authority.CertPEM())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !parsed.Cert.Equal(authority.Cert) {
+		t.Error("Parsed certificate doesn't match the original")
+	}
+
+	other := newTestAuthority(t, KeyTypeECDSA, nil)
+
+	otherKey, err := other.KeyPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ParseAuthority(otherKey, authority.CertPEM())
+	if err == nil {
+		t.Error("A key that doesn't match the certificate should be rejected")
+	}
+}
+
+func TestParseAuthorityPKCS8(t *testing.T) {
+
+	authority := newTestAuthority(t, KeyTypeRSA, nil)
+
+	der, err := x509.MarshalPKCS8PrivateKey(authority.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyContents := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	_, err = ParseAuthority(keyContents, authority.CertPEM())
+	if err != nil {
+		t.Errorf("PKCS #8 keys should be supported: %s", err)
+	}
+}
+
+func TestLoadAuthority(t *testing.T) {
+
+	storage := NewMemoryStorage()
+
+	_, err := LoadAuthority(storage, "root.key", "root.pem")
+	if err == nil {
+		t.Error("Loading a missing CA should fail")
+	}
+
+	authority := newTestAuthority(t, KeyTypeECDSA, nil)
+
+	err = authority.Save(storage, "root.pem", "root.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadAuthority(storage, "root.key", "root.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !loaded.Cert.Equal(authority.Cert) {
+		t.Error("Loaded certificate doesn't match the saved one")
+	}
+
+	err = authority.Save(storage, "root.pem", "root.key")
+	if err == nil {
+		t.Error("Saving should never replace existing files")
+	}
+}
+
+func TestGenCerts(t *testing.T) {
+
+	certDir := t.TempDir()
+
+	certInfo := CertInfo{
+		CertDir:          certDir,
+		CertDomain:       "sites.kana.li",
+		RootKey:          "kana.root.key",
+		RootCert:         "kana.root.pem",
+		SiteCert:         "kana.site.pem",
+		SiteKey:          "kana.site.key",
+		PermittedDomains: []string{"sites.kana.li"},
+	}
+
+	err := GenCerts(certInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{"kana.root.key", "kana.root.pem", "kana.site.key", "kana.site.pem"} {
+
+		info, err := os.Stat(path.Join(certDir, file))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("%s should only be readable by the current user but has mode %s", file, info.Mode().Perm())
+		}
+	}
+
+	// A second certificate should be signed by the existing root
+	certInfo.SiteCert = "mysite.pem"
+	certInfo.SiteKey = "mysite.key"
+	certInfo.Domains = []string{"mysite.sites.kana.li"}
+
+	err = GenCerts(certInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage := FileStorage{Dir: certDir}
+
+	authority, err := LoadAuthority(storage, "kana.root.key", "kana.root.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for file, name := range map[string]string{"kana.site.pem": "mysite.sites.kana.li", "mysite.pem": "mysite.sites.kana.li"} {
+
+		contents, err := storage.Read(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cert, err := readCert(contents)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = cert.Verify(x509.VerifyOptions{
+			DNSName: name,
+			Roots:   getRoots(authority),
+		})
+		if err != nil {
+			t.Errorf("%s should be signed by the existing root: %s", file, err)
+		}
+	}
+
+	err = GenCerts(certInfo)
+	if err == nil {
+		t.Error("GenCerts should not replace an existing certificate")
+	}
+}
+
+func TestGenCertsExternalCA(t *testing.T) {
+
+	certDir := t.TempDir()
+
+	certInfo := CertInfo{
+		CertDir:    certDir,
+		CertDomain: "sites.kana.li",
+		RootKey:    path.Join(certDir, "rootCA-key.pem"),
+		RootCert:   path.Join(certDir, "rootCA.pem"),
+		SiteCert:   "kana.site.pem",
+		SiteKey:    "kana.site.key",
+		ExternalCA: true,
+	}
+
+	err := GenCerts(certInfo)
+	if err == nil {
+		t.Error("GenCerts should fail when the external CA is missing")
+	}
+
+	_, err = os.Stat(certInfo.RootCert)
+	if !os.IsNotExist(err) {
+		t.Error("GenCerts should never create an external CA")
+	}
+
+	authority := newTestAuthority(t, KeyTypeRSA, nil)
+
+	err = authority.Save(FileStorage{}, certInfo.RootCert, certInfo.RootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = GenCerts(certInfo)
+	if err != nil {
+		t.Errorf("GenCerts should sign with the external CA: %s", err)
+	}
+}
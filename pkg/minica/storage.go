@@ -0,0 +1,87 @@
+package minica
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"sync"
+)
+
+// Storage Persists keys and certificates by name
+type Storage interface {
+	// Read returns the contents stored under name or an error wrapping fs.ErrNotExist if there are none
+	Read(name string) ([]byte, error)
+	// Write stores data under name, failing with an error wrapping fs.ErrExist rather than replacing anything
+	Write(name string, data []byte) error
+}
+
+// FileStorage Stores keys and certificates as files in a directory. Names are used as is when Dir is empty.
+type FileStorage struct {
+	Dir string
+}
+
+// MemoryStorage Stores keys and certificates in memory
+type MemoryStorage struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+// Read Reads a file from the directory
+func (f FileStorage) Read(name string) ([]byte, error) {
+
+	return os.ReadFile(path.Join(f.Dir, name))
+}
+
+// Write Creates a new file in the directory that only the current user can read
+func (f FileStorage) Write(name string, data []byte) error {
+
+	file, err := os.OpenFile(path.Join(f.Dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// NewMemoryStorage Returns an empty in-memory storage
+func NewMemoryStorage() *MemoryStorage {
+
+	return &MemoryStorage{
+		files: map[string][]byte{},
+	}
+}
+
+// Read Returns a copy of the contents stored under name
+func (m *MemoryStorage) Read(name string) ([]byte, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return append([]byte{}, data...), nil
+}
+
+// Write Stores a copy of data under a name that isn't in use yet
+func (m *MemoryStorage) Write(name string, data []byte) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.files[name]; ok {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrExist}
+	}
+
+	m.files[name] = append([]byte{}, data...)
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package minica
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func testStorage(t *testing.T, storage Storage) {
+
+	_, err := storage.Read("kana.root.pem")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Reading a missing file should return fs.ErrNotExist but got %v", err)
+	}
+
+	err = storage.Write("kana.root.pem", []byte("root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = storage.Write("kana.root.pem", []byte("replaced"))
+	if !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Writing an existing file should return fs.ErrExist but got %v", err)
+	}
+
+	contents, err := storage.Read("kana.root.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(contents) != "root" {
+		t.Errorf("Expected the original contents but got %s", contents)
+	}
+}
+
+func TestFileStorage(t *testing.T) {
+
+	testStorage(t, FileStorage{Dir: t.TempDir()})
+}
+
+func TestMemoryStorage(t *testing.T) {
+
+	storage := NewMemoryStorage()
+
+	testStorage(t, storage)
+
+	// Callers shouldn't be able to change what is stored through the returned slice
+	contents, err := storage.Read("kana.root.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents[0] = 'R'
+
+	contents, err = storage.Read("kana.root.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(contents) != "root" {
+		t.Errorf("Stored contents were changed to %s", contents)
+	}
+}
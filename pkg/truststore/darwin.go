@@ -0,0 +1,61 @@
+package truststore
+
+import (
+	"crypto/sha1"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// Darwin installs and removes a root certificate in the macOS system keychain
+type Darwin struct {
+	Keychain string
+	Run      Runner
+}
+
+// NewDarwin Returns the macOS system keychain
+func NewDarwin() *Darwin {
+
+	return &Darwin{
+		Keychain: "/Library/Keychains/System.keychain",
+		Run:      runCommand,
+	}
+}
+
+// Install Adds the certificate to the system keychain and trusts it as a root
+func (d *Darwin) Install(certFile string) ([]string, error) {
+
+	err := d.Run("sudo", "security", "add-trusted-cert", "-d", "-r", "trustRoot", "-k", d.Keychain, certFile)
+	if err != nil {
+		return []string{}, err
+	}
+
+	return []string{d.Keychain}, nil
+}
+
+// Uninstall Removes the certificate and its trust settings from the system keychain
+func (d *Darwin) Uninstall(certFile string) ([]string, error) {
+
+	certContents, err := os.ReadFile(certFile)
+	if err != nil {
+		return []string{}, err
+	}
+
+	block, _ := pem.Decode(certContents)
+	if block == nil {
+		return []string{}, fmt.Errorf("no certificate found in %s", certFile)
+	}
+
+	err = d.Run("sudo", "security", "remove-trusted-cert", "-d", certFile)
+	if err != nil {
+		return []string{}, err
+	}
+
+	// Delete by hash so we never remove another certificate that shares the same name
+	err = d.Run("sudo", "security", "delete-certificate", "-Z", fmt.Sprintf("%X", sha1.Sum(block.Bytes)), d.Keychain)
+	if err != nil {
+		return []string{}, err
+	}
+
+	return []string{d.Keychain}, nil
+}
@@ -0,0 +1,238 @@
+package truststore
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Linux installs and removes a root certificate in the system trust store and the NSS databases used by Firefox and Chromium
+type Linux struct {
+	Root     string // The filesystem root. This is "/" outside of tests.
+	Home     string
+	Name     string // The nickname of the certificate in NSS databases
+	FileName string // The file name, without extension, used in the system store
+	UseSudo  bool
+	Run      Runner
+	LookPath func(file string) (string, error)
+}
+
+type systemStore struct {
+	name, anchorDir, extension string
+	install, uninstall         []string
+}
+
+// The system stores Kana knows how to update, detected by their anchor directories
+var systemStores = []systemStore{
+	{
+		name:      "update-ca-certificates",
+		anchorDir: "usr/local/share/ca-certificates",
+		extension: ".crt",
+		install:   []string{"update-ca-certificates"},
+		uninstall: []string{"update-ca-certificates", "--fresh"},
+	},
+	{
+		name:      "update-ca-trust",
+		anchorDir: "etc/pki/ca-trust/source/anchors",
+		extension: ".pem",
+		install:   []string{"update-ca-trust", "extract"},
+		uninstall: []string{"update-ca-trust", "extract"},
+	},
+}
+
+// The NSS databases used by browsers, relative to the user's home directory
+var nssDatabaseGlobs = []string{
+	".pki/nssdb",
+	"snap/chromium/current/.pki/nssdb",
+	".mozilla/firefox/*",
+	"snap/firefox/common/.mozilla/firefox/*",
+}
+
+// NewLinux Returns a Linux trust store for the current system and user
+func NewLinux(home, name, fileName string) *Linux {
+
+	return &Linux{
+		Root:     "/",
+		Home:     home,
+		Name:     name,
+		FileName: fileName,
+		UseSudo:  os.Geteuid() != 0,
+		Run:      runCommand,
+		LookPath: exec.LookPath,
+	}
+}
+
+// Install Adds the certificate to every trust store found, returning the stores it was added to
+func (l *Linux) Install(certFile string) ([]string, error) {
+
+	installed := []string{}
+
+	for _, store := range l.findSystemStores() {
+
+		err := l.writeAnchor(store, certFile)
+		if err != nil {
+			return installed, err
+		}
+
+		err = l.runPrivileged(store.install...)
+		if err != nil {
+			return installed, err
+		}
+
+		installed = append(installed, store.name)
+	}
+
+	databases, err := l.findNSSDatabases()
+	if err != nil || len(databases) == 0 {
+		return installed, err
+	}
+
+	if _, err = l.LookPath("certutil"); err != nil {
+		return installed, fmt.Errorf("certutil is needed to trust the certificate in Firefox and Chromium. Please install libnss3-tools (Debian and Ubuntu) or nss-tools (Fedora) and try again")
+	}
+
+	for _, database := range databases {
+
+		err = l.Run("certutil", "-A", "-d", fmt.Sprintf("sql:%s", database), "-t", "C,,", "-n", l.Name, "-i", certFile)
+		if err != nil {
+			return installed, err
+		}
+
+		installed = append(installed, database)
+	}
+
+	return installed, nil
+}
+
+// Uninstall Removes the certificate from every trust store found, returning the stores it was removed from. Certificates are found by name so certFile is unused.
+func (l *Linux) Uninstall(certFile string) ([]string, error) {
+
+	removed := []string{}
+
+	for _, store := range l.findSystemStores() {
+
+		anchor := l.anchorPath(store)
+
+		if _, err := os.Stat(anchor); os.IsNotExist(err) {
+			continue
+		}
+
+		err := l.removeAnchor(anchor)
+		if err != nil {
+			return removed, err
+		}
+
+		err = l.runPrivileged(store.uninstall...)
+		if err != nil {
+			return removed, err
+		}
+
+		removed = append(removed, store.name)
+	}
+
+	databases, err := l.findNSSDatabases()
+	if err != nil || len(databases) == 0 {
+		return removed, err
+	}
+
+	if _, err = l.LookPath("certutil"); err != nil {
+		return removed, fmt.Errorf("certutil is needed to remove the certificate from Firefox and Chromium. Please install libnss3-tools (Debian and Ubuntu) or nss-tools (Fedora) and try again")
+	}
+
+	for _, database := range databases {
+
+		// Only delete the certificate from databases that actually contain it
+		if l.Run("certutil", "-L", "-d", fmt.Sprintf("sql:%s", database), "-n", l.Name) != nil {
+			continue
+		}
+
+		err = l.Run("certutil", "-D", "-d", fmt.Sprintf("sql:%s", database), "-n", l.Name)
+		if err != nil {
+			return removed, err
+		}
+
+		removed = append(removed, database)
+	}
+
+	return removed, nil
+}
+
+// anchorPath Returns the path of the certificate in the given system store
+func (l *Linux) anchorPath(store systemStore) string {
+
+	return filepath.Join(l.Root, store.anchorDir, l.FileName+store.extension)
+}
+
+// findNSSDatabases Returns every NSS database in the user's home directory
+func (l *Linux) findNSSDatabases() ([]string, error) {
+
+	databases := []string{}
+
+	for _, nssGlob := range nssDatabaseGlobs {
+
+		matches, err := filepath.Glob(filepath.Join(l.Home, nssGlob, "cert9.db"))
+		if err != nil {
+			return databases, err
+		}
+
+		for _, match := range matches {
+			databases = append(databases, filepath.Dir(match))
+		}
+	}
+
+	return databases, nil
+}
+
+// findSystemStores Returns the system stores present on this machine
+func (l *Linux) findSystemStores() []systemStore {
+
+	stores := []systemStore{}
+
+	for _, store := range systemStores {
+
+		info, err := os.Stat(filepath.Join(l.Root, store.anchorDir))
+		if err == nil && info.IsDir() {
+			stores = append(stores, store)
+		}
+	}
+
+	return stores
+}
+
+// removeAnchor Removes the certificate from a system store's anchor directory
+func (l *Linux) removeAnchor(anchor string) error {
+
+	if l.UseSudo {
+		return l.Run("sudo", "rm", "-f", anchor)
+	}
+
+	return os.Remove(anchor)
+}
+
+// runPrivileged Runs a command as root, using sudo if needed
+func (l *Linux) runPrivileged(command ...string) error {
+
+	if l.UseSudo {
+		return l.Run("sudo", command...)
+	}
+
+	return l.Run(command[0], command[1:]...)
+}
+
+// writeAnchor Writes the certificate to a system store's anchor directory
+func (l *Linux) writeAnchor(store systemStore, certFile string) error {
+
+	anchor := l.anchorPath(store)
+
+	if l.UseSudo {
+		return l.Run("sudo", "install", "-m", "0644", certFile, anchor)
+	}
+
+	certContents, err := os.ReadFile(certFile)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(anchor, certContents, 0644)
+}
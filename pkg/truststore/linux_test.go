@@ -0,0 +1,218 @@
+package truststore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestLinux Returns a Linux trust store rooted in temporary directories that records the commands it runs
+func newTestLinux(t *testing.T, hasCertutil bool) (*Linux, *[]string) {
+
+	commands := []string{}
+
+	l := &Linux{
+		Root:     t.TempDir(),
+		Home:     t.TempDir(),
+		Name:     "Kana Development CA",
+		FileName: "kana-development-ca",
+		Run: func(name string, args ...string) error {
+			commands = append(commands, strings.Join(append([]string{name}, args...), " "))
+			return nil
+		},
+		LookPath: func(file string) (string, error) {
+			if hasCertutil {
+				return filepath.Join("/usr/bin", file), nil
+			}
+			return "", fmt.Errorf("%s not found", file)
+		},
+	}
+
+	return l, &commands
+}
+
+func mkdir(t *testing.T, parts ...string) string {
+
+	dir := filepath.Join(parts...)
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func writeCert(t *testing.T) string {
+
+	certFile := filepath.Join(t.TempDir(), "kana.root.pem")
+
+	err := os.WriteFile(certFile, []byte("-----BEGIN CERTIFICATE-----\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return certFile
+}
+
+func TestInstallDebian(t *testing.T) {
+
+	l, commands := newTestLinux(t, true)
+	mkdir(t, l.Root, "usr/local/share/ca-certificates")
+
+	installed, err := l.Install(writeCert(t))
+	if err != nil {
+		t.Error(err)
+	}
+
+	anchor := filepath.Join(l.Root, "usr/local/share/ca-certificates", "kana-development-ca.crt")
+	if _, err = os.Stat(anchor); err != nil {
+		t.Errorf("Expected the certificate to be written to %s", anchor)
+	}
+
+	if len(installed) != 1 || installed[0] != "update-ca-certificates" {
+		t.Errorf("Expected only update-ca-certificates to be installed; got %v", installed)
+	}
+
+	if len(*commands) != 1 || (*commands)[0] != "update-ca-certificates" {
+		t.Errorf("Expected update-ca-certificates to run; got %v", *commands)
+	}
+}
+
+func TestInstallFedora(t *testing.T) {
+
+	l, commands := newTestLinux(t, true)
+	mkdir(t, l.Root, "etc/pki/ca-trust/source/anchors")
+
+	_, err := l.Install(writeCert(t))
+	if err != nil {
+		t.Error(err)
+	}
+
+	anchor := filepath.Join(l.Root, "etc/pki/ca-trust/source/anchors", "kana-development-ca.pem")
+	if _, err = os.Stat(anchor); err != nil {
+		t.Errorf("Expected the certificate to be written to %s", anchor)
+	}
+
+	if len(*commands) != 1 || (*commands)[0] != "update-ca-trust extract" {
+		t.Errorf("Expected update-ca-trust to run; got %v", *commands)
+	}
+}
+
+func TestInstallWithSudo(t *testing.T) {
+
+	l, commands := newTestLinux(t, true)
+	l.UseSudo = true
+	mkdir(t, l.Root, "usr/local/share/ca-certificates")
+	certFile := writeCert(t)
+
+	_, err := l.Install(certFile)
+	if err != nil {
+		t.Error(err)
+	}
+
+	anchor := filepath.Join(l.Root, "usr/local/share/ca-certificates", "kana-development-ca.crt")
+	expected := []string{
+		fmt.Sprintf("sudo install -m 0644 %s %s", certFile, anchor),
+		"sudo update-ca-certificates",
+	}
+
+	if strings.Join(*commands, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %v; got %v", expected, *commands)
+	}
+}
+
+func TestInstallNSS(t *testing.T) {
+
+	l, commands := newTestLinux(t, true)
+	certFile := writeCert(t)
+
+	chromium := mkdir(t, l.Home, ".pki/nssdb")
+	firefox := mkdir(t, l.Home, ".mozilla/firefox/abcd1234.default-release")
+	noDatabase := mkdir(t, l.Home, ".mozilla/firefox/empty")
+
+	for _, database := range []string{chromium, firefox} {
+		err := os.WriteFile(filepath.Join(database, "cert9.db"), []byte{}, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	installed, err := l.Install(certFile)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(installed) != 2 {
+		t.Errorf("Expected 2 NSS databases; got %v", installed)
+	}
+
+	for _, command := range *commands {
+		if strings.Contains(command, noDatabase) {
+			t.Errorf("Profile without a database should have been skipped; got %s", command)
+		}
+	}
+
+	expected := fmt.Sprintf("certutil -A -d sql:%s -t C,, -n Kana Development CA -i %s", firefox, certFile)
+	if len(*commands) != 2 || (*commands)[1] != expected {
+		t.Errorf("Expected %q; got %v", expected, *commands)
+	}
+}
+
+func TestInstallNSSWithoutCertutil(t *testing.T) {
+
+	l, commands := newTestLinux(t, false)
+	mkdir(t, l.Root, "usr/local/share/ca-certificates")
+	database := mkdir(t, l.Home, ".pki/nssdb")
+
+	err := os.WriteFile(filepath.Join(database, "cert9.db"), []byte{}, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	installed, err := l.Install(writeCert(t))
+	if err == nil {
+		t.Errorf("Expected an error when certutil is missing")
+	}
+
+	if len(installed) != 1 || len(*commands) != 1 {
+		t.Errorf("The system store should still have been installed; got %v", installed)
+	}
+}
+
+func TestUninstall(t *testing.T) {
+
+	l, commands := newTestLinux(t, true)
+	anchorDir := mkdir(t, l.Root, "usr/local/share/ca-certificates")
+	mkdir(t, l.Root, "etc/pki/ca-trust/source/anchors")
+	certFile := writeCert(t)
+
+	_, err := l.Install(certFile)
+	if err != nil {
+		t.Error(err)
+	}
+
+	*commands = []string{}
+
+	// Remove the Fedora anchor to make sure stores without the certificate are skipped
+	os.Remove(filepath.Join(l.Root, "etc/pki/ca-trust/source/anchors", "kana-development-ca.pem"))
+
+	removed, err := l.Uninstall(certFile)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, err = os.Stat(filepath.Join(anchorDir, "kana-development-ca.crt")); !os.IsNotExist(err) {
+		t.Errorf("Expected the certificate to be removed")
+	}
+
+	if len(removed) != 1 || removed[0] != "update-ca-certificates" {
+		t.Errorf("Expected only update-ca-certificates to be removed; got %v", removed)
+	}
+
+	if len(*commands) != 1 || (*commands)[0] != "update-ca-certificates --fresh" {
+		t.Errorf("Expected update-ca-certificates --fresh to run; got %v", *commands)
+	}
+}
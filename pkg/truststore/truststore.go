@@ -0,0 +1,40 @@
+package truststore
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// Runner runs an external command, returning an error if it fails
+type Runner func(name string, args ...string) error
+
+// Store adds and removes a root certificate from the trust stores on the current platform
+type Store interface {
+	Install(certFile string) ([]string, error)
+	Uninstall(certFile string) ([]string, error)
+}
+
+// New Returns the trust store for the current platform
+func New(home, name, fileName string) (Store, error) {
+
+	switch runtime.GOOS {
+	case "darwin":
+		return NewDarwin(), nil
+	case "linux":
+		return NewLinux(home, name, fileName), nil
+	}
+
+	return nil, fmt.Errorf("installing certificates is not supported on %s", runtime.GOOS)
+}
+
+// runCommand Runs a command attached to the user's terminal so sudo can prompt for a password
+func runCommand(name string, args ...string) error {
+
+	command := exec.Command(name, args...)
+	command.Stdin = os.Stdin
+	command.Stderr = os.Stderr
+
+	return command.Run()
+}